@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/jroimartin/gocui"
+	zen_doctor "github.com/krixi/zen-doctor/internal"
+	"github.com/pkg/errors"
+)
+
+const (
+	editorView  = "editor"
+	fieldsView  = "fields"
+	brushesView = "brushes"
+	statusView  = "status"
+)
+
+// errTestPlay stops the editor so the level can be played.
+var errTestPlay = errors.New("test play")
+
+// editLevel runs the level editor until the player quits. Test playing closes the editor, runs the game with just
+// the level being edited, and then comes back to the editor when the game is quit.
+func editLevel(path string) error {
+	editor, err := zen_doctor.NewEditor(path, mode)
+	if err != nil {
+		return err
+	}
+	for {
+		err := runEditor(editor)
+		if err != errTestPlay {
+			return err
+		}
+		test, err := editor.Campaign()
+		if err != nil {
+			editor.Status = err.Error()
+			continue
+		}
+		campaign = test
+		if err := play(); err != nil {
+			return err
+		}
+		editor.Status = "Back from test play"
+	}
+}
+
+func runEditor(editor *zen_doctor.Editor) error {
+	g, err := gocui.NewGui(gocui.Output256)
+	if err != nil {
+		return err
+	}
+	defer g.Close()
+
+	if mode == zen_doctor.CompatibilityAscii {
+		g.ASCII = true
+	}
+	g.Highlight = true
+	g.SelFgColor = gocui.ColorGreen
+	g.SetManagerFunc(editorLayout(editor))
+	if err := editorKeybinds(g, editor); err != nil {
+		return err
+	}
+
+	if err := g.MainLoop(); err != nil && err != gocui.ErrQuit {
+		return err
+	}
+	return nil
+}
+
+func editorLayout(editor *zen_doctor.Editor) func(g *gocui.Gui) error {
+	return func(g *gocui.Gui) error {
+		maxX, maxY := g.Size()
+		level := editor.Level()
+		x1, y1, x2, y2 := zen_doctor.CalculateViewPosition(level.Width, level.Height, maxX, maxY)
+
+		v, err := g.SetView(editorView, x1, y1, x2, y2)
+		if err != nil {
+			if err != gocui.ErrUnknownView {
+				return err
+			}
+			g.SetCurrentView(editorView)
+		}
+		v.Title = level.Name()
+		v.Clear()
+		fmt.Fprintf(v, "%s", editor.String())
+
+		if v, err = g.SetView(fieldsView, x1-28, y1, x1-1, y2); err != nil && err != gocui.ErrUnknownView {
+			return err
+		}
+		v.Title = "Level"
+		v.Clear()
+		fmt.Fprintf(v, "%s", editor.Fields())
+
+		if v, err = g.SetView(brushesView, x2+1, y1, x2+16, y2); err != nil && err != gocui.ErrUnknownView {
+			return err
+		}
+		v.Title = "Brush"
+		v.Clear()
+		fmt.Fprintf(v, "%s", editor.BrushNames())
+		pen := "off"
+		if editor.Pen {
+			pen = "on"
+		}
+		fmt.Fprintf(v, "\nPen %s\n", pen)
+
+		if v, err = g.SetView(statusView, x1, y2+1, x2, y2+4); err != nil && err != gocui.ErrUnknownView {
+			return err
+		}
+		v.Title = editor.Status
+		v.Clear()
+		fmt.Fprintln(v, "arrows move, space paints, p toggles the pen, 1-8 pick a brush")
+		fmt.Fprintln(v, "tab or [ ] pick a number, +/- change it, ctrl-s saves, t test plays")
+		return nil
+	}
+}
+
+func editorKeybinds(g *gocui.Gui, editor *zen_doctor.Editor) error {
+	bind := func(key interface{}, handler func()) error {
+		return g.SetKeybinding(editorView, key, gocui.ModNone, func(_ *gocui.Gui, _ *gocui.View) error {
+			handler()
+			return nil
+		})
+	}
+
+	if err := g.SetKeybinding("", gocui.KeyCtrlC, gocui.ModNone, func(_ *gocui.Gui, _ *gocui.View) error {
+		return gocui.ErrQuit
+	}); err != nil {
+		return err
+	}
+	moves := map[gocui.Key]zen_doctor.Direction{
+		gocui.KeyArrowUp:    zen_doctor.MoveUp,
+		gocui.KeyArrowDown:  zen_doctor.MoveDown,
+		gocui.KeyArrowLeft:  zen_doctor.MoveLeft,
+		gocui.KeyArrowRight: zen_doctor.MoveRight,
+	}
+	for key, dir := range moves {
+		dir := dir
+		if err := bind(key, func() { editor.MoveCursor(dir) }); err != nil {
+			return err
+		}
+	}
+	for i, brush := range zen_doctor.Brushes {
+		brush := brush
+		if err := bind(rune('1'+i), func() { editor.SetBrush(brush) }); err != nil {
+			return err
+		}
+	}
+	if err := bind(gocui.KeySpace, editor.Paint); err != nil {
+		return err
+	}
+	if err := bind('p', editor.TogglePen); err != nil {
+		return err
+	}
+	if err := bind(gocui.KeyTab, func() { editor.SelectField(1) }); err != nil {
+		return err
+	}
+	if err := bind('[', func() { editor.SelectField(-1) }); err != nil {
+		return err
+	}
+	if err := bind(']', func() { editor.SelectField(1) }); err != nil {
+		return err
+	}
+	for _, key := range []rune{'+', '='} {
+		if err := bind(key, func() { editor.Adjust(1) }); err != nil {
+			return err
+		}
+	}
+	if err := bind('-', func() { editor.Adjust(-1) }); err != nil {
+		return err
+	}
+	if err := bind(gocui.KeyCtrlS, func() {
+		if err := editor.Save(); err != nil {
+			editor.Status = err.Error()
+		}
+	}); err != nil {
+		return err
+	}
+	return g.SetKeybinding(editorView, 't', gocui.ModNone, func(_ *gocui.Gui, _ *gocui.View) error {
+		return errTestPlay
+	})
+}
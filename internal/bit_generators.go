@@ -0,0 +1,247 @@
+package zen_doctor
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// BitGenerator makes the new bits that flow into the bit stream.
+type BitGenerator interface {
+	// Next is called each time the bit stream moves, so what gets made can change over time.
+	Next(rng *rand.Rand)
+	// Bit makes a new bit for the cell at c.
+	Bit(rng *rand.Rand, level *LevelConfig, c Coordinate) Bits
+}
+
+// newBits makes a bit of the given kind, with a random value and rarity.
+func newBits(rng *rand.Rand, level *LevelConfig, revealed RevealedBitType) Bits {
+	hidden := hiddenBits[rng.Intn(len(hiddenBits))]
+	rarity := getRarity(rng, level)
+	return Bits{Hidden: hidden, Revealed: revealed, Value: rarity, RevealedSymbol: GenerateNoiseSymbolFor(revealed, rarity)}
+}
+
+// randomBits rolls every bit on its own, so bad bits come out as noise. It's used when a level doesn't say otherwise.
+type randomBits struct{}
+
+func (randomBits) Next(_ *rand.Rand) {}
+
+func (randomBits) Bit(rng *rand.Rand, level *LevelConfig, _ Coordinate) Bits {
+	return getBit(rng, level)
+}
+
+// noiseBits uses coherent noise to clump bad bits together, and good bits together somewhere else.
+type noiseBits struct {
+	noise *perlin
+	size  float64 // roughly how many cells across a clump is
+	drift float64 // how fast the clumps change shape as the bit stream moves
+	t     float64
+
+	// the noise is always about the same shape, so these are worked out once to get the level's chances about right
+	calibrated bool
+	bad, good  float64
+}
+
+func newNoiseBits(seed int64, size, drift float64) *noiseBits {
+	return &noiseBits{noise: newPerlin(seed), size: size, drift: drift}
+}
+
+func (g *noiseBits) Next(_ *rand.Rand) {
+	g.t += g.drift
+}
+
+func (g *noiseBits) Bit(rng *rand.Rand, level *LevelConfig, c Coordinate) Bits {
+	if !g.calibrated {
+		g.calibrate(level)
+	}
+	if rng.Float32() >= level.BitStreamChance {
+		return Bits{Hidden: BitTypeEmpty, Revealed: RevealedBitBenign, Value: Junk}
+	}
+	// cells are twice as tall as they are wide, so the clumps are squashed to look round
+	n := g.noise.at(float64(c.X)/g.size, 2*float64(c.Y)/g.size, g.t)
+	switch {
+	case n > g.bad:
+		return newBits(rng, level, RevealedBitHarmful)
+	case n < g.good:
+		return newBits(rng, level, RevealedBitHelpful)
+	}
+	return newBits(rng, level, RevealedBitBenign)
+}
+
+func (g *noiseBits) calibrate(level *LevelConfig) {
+	rng := rand.New(rand.NewSource(1))
+	samples := make([]float64, 1024)
+	for i := range samples {
+		samples[i] = g.noise.at(rng.Float64()*64, rng.Float64()*64, rng.Float64()*64)
+	}
+	sort.Float64s(samples)
+	quantile := func(q float32) float64 {
+		i := int(q * float32(len(samples)))
+		if i >= len(samples) {
+			i = len(samples) - 1
+		}
+		return samples[i]
+	}
+	g.bad = quantile(1 - level.BadBitChance)
+	g.good = quantile(level.GoodBitChance)
+	if level.GoodBitChance == 0 {
+		g.good = math.Inf(-1)
+	}
+	if level.BadBitChance == 0 {
+		g.bad = math.Inf(1)
+	}
+	g.calibrated = true
+}
+
+// packetBits sends bad bits through in square packets that travel together. Outside of packets, bits are never bad.
+type packetBits struct {
+	chance  float32 // chance of a new packet each time the bit stream moves
+	size    int     // how many cells across a packet is
+	width   int
+	height  int
+	packets []packet
+}
+
+// packet is a square of bad bits coming into the bit stream. New bits come in along the edges of the map, so a
+// packet covers a band of rows and a band of columns away from the edges, for as many moves as it is wide.
+type packet struct {
+	x, y int
+	left int
+}
+
+func (p packet) covers(c Coordinate, size int) bool {
+	return (c.X >= p.x && c.X < p.x+size) || (c.Y >= p.y && c.Y < p.y+size)
+}
+
+func newPacketBits(chance float32, size int) *packetBits {
+	return &packetBits{chance: chance, size: size}
+}
+
+func (g *packetBits) Next(rng *rand.Rand) {
+	active := g.packets[:0]
+	for _, p := range g.packets {
+		if p.left--; p.left > 0 {
+			active = append(active, p)
+		}
+	}
+	g.packets = active
+	// the size of the map isn't known until the first bits are made
+	if g.width > g.size+2 && g.height > g.size+2 && rng.Float32() < g.chance {
+		g.packets = append(g.packets, packet{
+			x:    1 + rng.Intn(g.width-g.size-1),
+			y:    1 + rng.Intn(g.height-g.size-1),
+			left: g.size,
+		})
+	}
+}
+
+func (g *packetBits) Bit(rng *rand.Rand, level *LevelConfig, c Coordinate) Bits {
+	g.width, g.height = level.Width, level.Height
+	for _, p := range g.packets {
+		if p.covers(c, g.size) {
+			return newBits(rng, level, RevealedBitHarmful)
+		}
+	}
+	bits := getBit(rng, level)
+	if bits.Revealed == RevealedBitHarmful {
+		bits = newBits(rng, level, RevealedBitBenign)
+	}
+	return bits
+}
+
+// wallBits every so often sends through a wall of bad bits, with a gap in it to slip through.
+type wallBits struct {
+	chance float32 // chance of a new wall each time the bit stream moves
+	gap    int     // how many cells wide the gap is
+	width  int
+	height int
+	wall   *packet // the gap in the wall, which works like a packet going the other way
+}
+
+func newWallBits(chance float32, gap int) *wallBits {
+	return &wallBits{chance: chance, gap: gap}
+}
+
+func (g *wallBits) Next(rng *rand.Rand) {
+	g.wall = nil
+	if g.width > g.gap+2 && g.height > g.gap+2 && rng.Float32() < g.chance {
+		g.wall = &packet{
+			x: 1 + rng.Intn(g.width-g.gap-1),
+			y: 1 + rng.Intn(g.height-g.gap-1),
+		}
+	}
+}
+
+func (g *wallBits) Bit(rng *rand.Rand, level *LevelConfig, c Coordinate) Bits {
+	g.width, g.height = level.Width, level.Height
+	if g.wall == nil {
+		return getBit(rng, level)
+	}
+	if g.wall.covers(c, g.gap) {
+		return Bits{Hidden: BitTypeEmpty, Revealed: RevealedBitBenign, Value: Junk}
+	}
+	return newBits(rng, level, RevealedBitHarmful)
+}
+
+// perlin is Ken Perlin's improved noise, with the gradients shuffled by a seed.
+type perlin struct {
+	perm [512]int
+}
+
+func newPerlin(seed int64) *perlin {
+	p := &perlin{}
+	for i, v := range rand.New(rand.NewSource(seed)).Perm(256) {
+		p.perm[i], p.perm[i+256] = v, v
+	}
+	return p
+}
+
+// at returns the noise at a point, somewhere between -1 and 1.
+func (p *perlin) at(x, y, z float64) float64 {
+	fx, fy, fz := math.Floor(x), math.Floor(y), math.Floor(z)
+	xi, yi, zi := int(fx)&255, int(fy)&255, int(fz)&255
+	x, y, z = x-fx, y-fy, z-fz
+	u, v, w := fade(x), fade(y), fade(z)
+
+	a := p.perm[xi] + yi
+	aa, ab := p.perm[a]+zi, p.perm[a+1]+zi
+	b := p.perm[xi+1] + yi
+	ba, bb := p.perm[b]+zi, p.perm[b+1]+zi
+
+	return lerp(w,
+		lerp(v,
+			lerp(u, grad(p.perm[aa], x, y, z), grad(p.perm[ba], x-1, y, z)),
+			lerp(u, grad(p.perm[ab], x, y-1, z), grad(p.perm[bb], x-1, y-1, z))),
+		lerp(v,
+			lerp(u, grad(p.perm[aa+1], x, y, z-1), grad(p.perm[ba+1], x-1, y, z-1)),
+			lerp(u, grad(p.perm[ab+1], x, y-1, z-1), grad(p.perm[bb+1], x-1, y-1, z-1))))
+}
+
+func fade(t float64) float64 {
+	return t * t * t * (t*(t*6-15) + 10)
+}
+
+func lerp(t, a, b float64) float64 {
+	return a + t*(b-a)
+}
+
+func grad(hash int, x, y, z float64) float64 {
+	h := hash & 15
+	u, v := x, y
+	if h >= 8 {
+		u = y
+	}
+	if h >= 4 {
+		v = z
+		if h == 12 || h == 14 {
+			v = x
+		}
+	}
+	if h&1 != 0 {
+		u = -u
+	}
+	if h&2 != 0 {
+		v = -v
+	}
+	return u + v
+}
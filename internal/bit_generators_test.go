@@ -0,0 +1,86 @@
+package zen_doctor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNoiseBits(t *testing.T) {
+	level := GetLevel(Tutorial)
+	rng := testRand()
+	level.BitStreamChance, level.BadBitChance, level.GoodBitChance = 1, 0.2, 0
+	g := newNoiseBits(42, 6, 0.1)
+
+	harmful, clumped := 0, 0
+	isHarmful := func(c Coordinate) bool {
+		return g.Bit(rng, &level, c).Revealed == RevealedBitHarmful
+	}
+	for x := 0; x < level.Width; x++ {
+		for y := 0; y < level.Height; y++ {
+			if isHarmful(Coordinate{x, y}) {
+				harmful++
+				if isHarmful(Coordinate{x + 1, y}) {
+					clumped++
+				}
+			}
+		}
+	}
+	cells := float64(level.Width * level.Height)
+	assert.InDelta(t, 0.2, float64(harmful)/cells, 0.1, "about as many bad bits as the level asks for")
+	assert.Greater(t, float64(clumped)/float64(harmful), 0.6, "bad bits are next to each other")
+
+	same := newNoiseBits(42, 6, 0.1)
+	for x := 0; x < 20; x++ {
+		c := Coordinate{x, 3}
+		assert.Equal(t, isHarmful(c), same.Bit(rng, &level, c).Revealed == RevealedBitHarmful, "the same seed makes the same noise")
+	}
+}
+
+func TestPacketBits(t *testing.T) {
+	level := GetLevel(Tutorial)
+	rng := testRand()
+	level.BitStreamChance, level.BadBitChance = 1, 1
+	g := newPacketBits(1, 3)
+
+	// nothing is bad until a packet turns up
+	for y := 0; y < level.Height; y++ {
+		assert.NotEqual(t, RevealedBitHarmful, g.Bit(rng, &level, Coordinate{0, y}).Revealed)
+	}
+	g.Next(rng)
+	p := g.packets[0]
+	for y := 0; y < level.Height; y++ {
+		harmful := g.Bit(rng, &level, Coordinate{0, y}).Revealed == RevealedBitHarmful
+		assert.Equal(t, y >= p.y && y < p.y+3, harmful, "row %d", y)
+	}
+
+	g.chance = 0
+	for i := 0; i < 3; i++ {
+		g.Next(rng)
+	}
+	assert.Empty(t, g.packets, "packets are as long as they are wide")
+}
+
+func TestWallBits(t *testing.T) {
+	level := GetLevel(Tutorial)
+	rng := testRand()
+	level.BitStreamChance, level.BadBitChance = 1, 0
+	g := newWallBits(1, 4)
+	g.Bit(rng, &level, Coordinate{})
+	g.Next(rng)
+
+	gap := 0
+	for y := 0; y < level.Height; y++ {
+		bits := g.Bit(rng, &level, Coordinate{level.Width - 1, y})
+		if bits.Hidden == BitTypeEmpty {
+			gap++
+		} else {
+			assert.Equal(t, RevealedBitHarmful, bits.Revealed)
+		}
+	}
+	assert.Equal(t, 4, gap)
+
+	g.chance = 0
+	g.Next(rng)
+	assert.NotEqual(t, RevealedBitHarmful, g.Bit(rng, &level, Coordinate{0, 0}).Revealed, "walls only last one move")
+}
@@ -0,0 +1,65 @@
+package zen_doctor
+
+// bitGrid holds the bits of the bit stream in a single slice, one for each cell of the map. Moving the whole grid
+// only moves where the origin is, so none of the bits have to be copied - the ones that fall off one edge wrap around
+// to the other, where they can be replaced with new ones.
+type bitGrid struct {
+	width, height    int
+	originX, originY int // where the top left cell of the map is in cells
+	cells            []Bits
+}
+
+func newBitGrid(width, height int) bitGrid {
+	return bitGrid{
+		width:  width,
+		height: height,
+		cells:  make([]Bits, width*height),
+	}
+}
+
+func (g *bitGrid) inBounds(c Coordinate) bool {
+	return c.X >= 0 && c.X < g.width && c.Y >= 0 && c.Y < g.height
+}
+
+func (g *bitGrid) index(c Coordinate) int {
+	x, y := c.X+g.originX, c.Y+g.originY
+	if x >= g.width {
+		x -= g.width
+	}
+	if y >= g.height {
+		y -= g.height
+	}
+	return y*g.width + x
+}
+
+// at returns the bits in the cell at c, or nothing if c is off the map.
+func (g *bitGrid) at(c Coordinate) Bits {
+	if !g.inBounds(c) {
+		return Bits{}
+	}
+	return g.cells[g.index(c)]
+}
+
+func (g *bitGrid) set(c Coordinate, bits Bits) {
+	if g.inBounds(c) {
+		g.cells[g.index(c)] = bits
+	}
+}
+
+// shift moves every bit one cell along in the given direction.
+func (g *bitGrid) shift(dir Direction) {
+	dx, dy := dir.Delta()
+	g.originX = wrap(g.originX-dx, g.width)
+	g.originY = wrap(g.originY-dy, g.height)
+}
+
+// wrap keeps v between 0 and n, for moves of one cell at a time.
+func wrap(v, n int) int {
+	if v < 0 {
+		return v + n
+	}
+	if v >= n {
+		return v - n
+	}
+	return v
+}
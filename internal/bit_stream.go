@@ -36,6 +36,8 @@ type Bits struct {
 	Revealed       RevealedBitType
 	Value          Rarity
 	RevealedSymbol AnimatedSymbol
+	id             uint64 // tells bits apart as they move, so they can be followed
+	spent          bool   // a bad bit that has already hit the player, and can't hit them again
 }
 
 func (b Bits) ViewHidden() string {
@@ -69,107 +71,184 @@ var hiddenBits = []HiddenBitType{
 	BitTypeOne,
 }
 
-func getBit(level *LevelConfig) Bits {
-	if rand.Float32() < level.BitStreamChance {
-		hidden := hiddenBits[rand.Intn(len(hiddenBits))]
-		rarity := getRarity(level)
-		next := rand.Float32()
+func getBit(rng *rand.Rand, level *LevelConfig) Bits {
+	if rng.Float32() < level.BitStreamChance {
+		next := rng.Float32()
 		revealed := RevealedBitBenign
 		if next < level.BadBitChance {
 			revealed = RevealedBitHarmful
 		} else if next > (1 - level.GoodBitChance) {
 			revealed = RevealedBitHelpful
 		}
-		revealedSymbol := GenerateNoiseSymbolFor(revealed, rarity)
-		return Bits{hidden, revealed, rarity, revealedSymbol}
+		return newBits(rng, level, revealed)
 	}
-	return Bits{BitTypeEmpty, RevealedBitBenign, Junk, nil}
+	return Bits{Hidden: BitTypeEmpty, Revealed: RevealedBitBenign, Value: Junk}
 }
 
 type BitStream struct {
-	level  *LevelConfig
-	stream map[Coordinate]Bits
+	level     *LevelConfig
+	grid      bitGrid
+	walls     map[Coordinate]bool
+	generator BitGenerator
+	sweep     sweep
+	lastID    uint64
+	rng       *rand.Rand
+	clock     Clock
+	started   time.Time // updaters time their steps from here, since the same updater is used each time a level is played
 }
 
-func newBitStream(level *LevelConfig) BitStream {
-	stream := make(map[Coordinate]Bits)
+func newBitStream(level *LevelConfig, walls map[Coordinate]bool, rng *rand.Rand, clock Clock) BitStream {
+	bs := BitStream{
+		level:     level,
+		grid:      newBitGrid(level.Width, level.Height),
+		walls:     walls,
+		generator: level.BitGenerator,
+		rng:       rng,
+		clock:     clock,
+		started:   clock.Now(),
+	}
+	if bs.generator == nil {
+		bs.generator = randomBits{}
+	}
 	for x := 0; x < level.Width; x++ {
 		for y := 0; y < level.Height; y++ {
 			c := Coordinate{x, y}
-			stream[c] = getBit(level)
+			if walls[c] || level.Layout.hasEmitters() {
+				// with emitters, the bit stream starts out empty and flows out of them
+				bs.grid.set(c, Bits{Hidden: BitTypeEmpty, Revealed: RevealedBitBenign, Value: Junk})
+			} else {
+				bs.grid.set(c, bs.newBit(c))
+			}
 		}
 	}
-	return BitStream{
-		level:  level,
-		stream: stream,
-	}
+	return bs
+}
+
+// newBit makes a bit to flow into the cell at c.
+func (bs *BitStream) newBit(c Coordinate) Bits {
+	b := bs.generator.Bit(bs.rng, bs.level, c)
+	bs.lastID++
+	b.id = bs.lastID
+	return b
+}
+
+// At returns the bits in the cell at c.
+func (bs *BitStream) At(c Coordinate) Bits {
+	return bs.grid.at(c)
 }
 
 func (bs *BitStream) TickAnimations() {
-	for c := range bs.stream {
-		if bs.stream[c].RevealedSymbol != nil {
-			bs.stream[c].RevealedSymbol.Tick()
+	for _, b := range bs.grid.cells {
+		if b.RevealedSymbol != nil {
+			b.RevealedSymbol.Tick(bs.rng)
 		}
 	}
 }
 
 func (bs *BitStream) DidCollideWithBit(level *LevelConfig, c Coordinate, bitType RevealedBitType) (float32, bool) {
-	if b, ok := bs.stream[c]; ok {
+	if bs.grid.inBounds(c) {
+		b := bs.grid.at(c)
 		return b.Threat(level), b.Revealed == bitType
 	}
 	return 0, false
 }
 
 func (bs *BitStream) NeutralizeBit(c Coordinate) {
-	if b, ok := bs.stream[c]; ok {
+	if bs.grid.inBounds(c) {
+		b := bs.grid.at(c)
 		b.Revealed = RevealedBitBenign
-		bs.stream[c] = b
+		bs.grid.set(c, b)
 	}
 }
 
 func shiftBitStream(dir Direction, stream *BitStream) {
-	newStream := make(map[Coordinate]Bits)
-
 	// Move all existing bits
-	for coord, val := range stream.stream {
-		var c Coordinate
-		switch dir {
-		case MoveDown:
-			c = Coordinate{X: coord.X, Y: coord.Y + 1}
-		case MoveUp:
-			c = Coordinate{X: coord.X, Y: coord.Y - 1}
-		case MoveLeft:
-			c = Coordinate{X: coord.X - 1, Y: coord.Y}
-		case MoveRight:
-			c = Coordinate{X: coord.X + 1, Y: coord.Y}
-		case MoveUpRight:
-			c = Coordinate{X: coord.X + 1, Y: coord.Y - 1}
-		case MoveUpLeft:
-			c = Coordinate{X: coord.X - 1, Y: coord.Y - 1}
-		case MoveDownRight:
-			c = Coordinate{X: coord.X + 1, Y: coord.Y + 1}
-		case MoveDownLeft:
-			c = Coordinate{X: coord.X - 1, Y: coord.Y + 1}
-		}
+	stream.grid.shift(dir)
 
-		if c.X < stream.level.Width && c.X >= 0 && c.Y < stream.level.Height && c.Y >= 0 {
-			newStream[c] = val
+	// the bits that fell off the edge wrapped around to the other side, where they get replaced
+	dx, dy := dir.Delta()
+	replace := func(c Coordinate) {
+		if stream.level.Layout.hasEmitters() {
+			stream.grid.set(c, Bits{Hidden: BitTypeEmpty, Revealed: RevealedBitBenign, Value: Junk})
 		} else {
-			// replace this bit
-			if c.X >= stream.level.Width {
-				c.X = 0
-			} else if c.X < 0 {
-				c.X = stream.level.Width - 1
+			stream.grid.set(c, stream.newBit(c))
+		}
+	}
+	column, row := -1, -1
+	if dx > 0 {
+		column = 0
+	} else if dx < 0 {
+		column = stream.level.Width - 1
+	}
+	if dy > 0 {
+		row = 0
+	} else if dy < 0 {
+		row = stream.level.Height - 1
+	}
+	if column >= 0 {
+		for y := 0; y < stream.level.Height; y++ {
+			replace(Coordinate{X: column, Y: y})
+		}
+	}
+	if row >= 0 {
+		for x := 0; x < stream.level.Width; x++ {
+			if x != column {
+				replace(Coordinate{X: x, Y: row})
 			}
-			if c.Y >= stream.level.Height {
-				c.Y = 0
-			} else if c.Y < 0 {
-				c.Y = stream.level.Height - 1
+		}
+	}
+	if stream.level.Layout.hasEmitters() {
+		for _, c := range stream.level.Layout.Emitters {
+			stream.grid.set(c, stream.newBit(c))
+		}
+	}
+
+	// bits can't pass through walls, which leaves a shadow behind them since nothing flows out of a wall.
+	for c := range stream.walls {
+		stream.grid.set(c, Bits{Hidden: BitTypeEmpty, Revealed: RevealedBitBenign, Value: Junk})
+	}
+	stream.sweepAll()
+}
+
+// shiftLane moves a single row or column of the bit stream one cell along, like shiftBitStream does for all of it.
+// Rows move left or right, and columns move up or down.
+func shiftLane(dir Direction, index int, stream *BitStream) {
+	dx, dy := dir.Delta()
+	step, length := dx, stream.level.Width
+	cell := func(i int) Coordinate { return Coordinate{X: i, Y: index} }
+	if dx == 0 {
+		step, length = dy, stream.level.Height
+		cell = func(i int) Coordinate { return Coordinate{X: index, Y: i} }
+	}
+
+	// walk against the flow, so each bit moves on before the one behind it takes its place
+	first, last := length-1, 0
+	if step < 0 {
+		first, last = 0, length-1
+	}
+	for i := first; i != last; i -= step {
+		stream.grid.set(cell(i), stream.grid.at(cell(i-step)))
+	}
+	// a new bit comes in at the edge the lane flows from
+	if stream.level.Layout.hasEmitters() {
+		stream.grid.set(cell(last), Bits{Hidden: BitTypeEmpty, Revealed: RevealedBitBenign, Value: Junk})
+	} else {
+		stream.grid.set(cell(last), stream.newBit(cell(last)))
+	}
+
+	if stream.level.Layout.hasEmitters() {
+		for _, c := range stream.level.Layout.Emitters {
+			if (dx != 0 && c.Y == index) || (dx == 0 && c.X == index) {
+				stream.grid.set(c, stream.newBit(c))
 			}
-			newStream[c] = getBit(stream.level)
 		}
 	}
-	stream.stream = newStream
+	for i := 0; i < length; i++ {
+		if c := cell(i); stream.walls[c] {
+			stream.grid.set(c, Bits{Hidden: BitTypeEmpty, Revealed: RevealedBitBenign, Value: Junk})
+		}
+	}
+	stream.sweepAll()
 }
 
 type BitStreamUpdater interface {
@@ -188,11 +267,53 @@ func (b *linearBitStream) Tick(stream *BitStream) {
 	shiftBitStream(b.dir, stream)
 }
 
+// laneBitStream moves each row, or each column, on its own. If there are fewer lanes than rows or columns, the
+// lanes repeat, so two lanes going opposite ways make for alternating traffic.
+type laneBitStream struct {
+	columns bool
+	lanes   []bitStreamLane
+}
+
+type bitStreamLane struct {
+	dir      Direction // left or right for rows, up or down for columns
+	movement movement  // how many cells the lane moves each tick - 0 keeps it still
+}
+
+func newLaneBitStream(columns bool, lanes ...bitStreamLane) *laneBitStream {
+	return &laneBitStream{columns: columns, lanes: lanes}
+}
+
+func (b *laneBitStream) Tick(stream *BitStream) {
+	count := stream.level.Height
+	if b.columns {
+		count = stream.level.Width
+	}
+	// every lane keeps its own progress, even when the pattern repeats
+	for pattern := len(b.lanes); len(b.lanes) < count; {
+		b.lanes = append(b.lanes, b.lanes[len(b.lanes)%pattern])
+	}
+	for i := 0; i < count; i++ {
+		lane := &b.lanes[i]
+		for steps := lane.movement.tick(); steps > 0; steps-- {
+			shiftLane(lane.dir, i, stream)
+		}
+	}
+}
+
+// staticBitStream never moves, for levels without a bit stream.
+type staticBitStream struct{}
+
+func newStaticBitStream() *staticBitStream {
+	return &staticBitStream{}
+}
+
+func (b *staticBitStream) Tick(_ *BitStream) {}
+
 type bitStreamWithSteps struct {
 	steps      []bitStreamStep
 	current    int
 	lastUpdate time.Time
-	next       func(current, len int) int
+	next       func(rng *rand.Rand, current, len int) int
 }
 
 type bitStreamStep struct {
@@ -202,9 +323,8 @@ type bitStreamStep struct {
 
 func newLoopingBitStream(steps ...bitStreamStep) *bitStreamWithSteps {
 	return &bitStreamWithSteps{
-		steps:      steps,
-		lastUpdate: time.Now(),
-		next: func(current, len int) int {
+		steps: steps,
+		next: func(_ *rand.Rand, current, len int) int {
 			if current+1 >= len {
 				return 0
 			}
@@ -215,18 +335,22 @@ func newLoopingBitStream(steps ...bitStreamStep) *bitStreamWithSteps {
 
 func newRandomBitStream(steps ...bitStreamStep) *bitStreamWithSteps {
 	return &bitStreamWithSteps{
-		steps:      steps,
-		lastUpdate: time.Now(),
-		next: func(_, len int) int {
-			return rand.Intn(len)
+		steps: steps,
+		next: func(rng *rand.Rand, _, len int) int {
+			return rng.Intn(len)
 		},
 	}
 }
 
 func (s *bitStreamWithSteps) Tick(stream *BitStream) {
-	now := time.Now()
+	now := stream.clock.Now()
+	if s.lastUpdate.Before(stream.started) {
+		// a new play of the level starts over from the first step
+		s.current = 0
+		s.lastUpdate = now
+	}
 	if now.Sub(s.lastUpdate) > s.steps[s.current].delay {
-		s.current = s.next(s.current, len(s.steps))
+		s.current = s.next(stream.rng, s.current, len(s.steps))
 		s.lastUpdate = now
 	}
 	shiftBitStream(s.steps[s.current].dir, stream)
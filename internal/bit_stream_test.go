@@ -0,0 +1,163 @@
+package zen_doctor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLaneBitStream(t *testing.T) {
+	level := GetLevel(Tutorial)
+	level.Width, level.Height = 10, 4
+	marked := Bits{Hidden: BitTypeOne, Value: -1} // no real bit has this value
+
+	tests := map[string]struct {
+		updater *laneBitStream
+		from    []Coordinate
+		to      []Coordinate
+	}{
+		"alternating rows": {
+			newLaneBitStream(false, bitStreamLane{dir: MoveRight, movement: movement{speed: 1}}, bitStreamLane{dir: MoveLeft, movement: movement{speed: 1}}),
+			[]Coordinate{{5, 0}, {5, 1}, {5, 2}, {5, 3}},
+			[]Coordinate{{7, 0}, {3, 1}, {7, 2}, {3, 3}},
+		},
+		"row speeds": {
+			newLaneBitStream(false, bitStreamLane{dir: MoveRight, movement: movement{speed: 0}}, bitStreamLane{dir: MoveRight, movement: movement{speed: 0.5}}, bitStreamLane{dir: MoveRight, movement: movement{speed: 2}}),
+			[]Coordinate{{0, 0}, {0, 1}, {0, 2}},
+			[]Coordinate{{0, 0}, {1, 1}, {4, 2}},
+		},
+		"columns": {
+			newLaneBitStream(true, bitStreamLane{dir: MoveDown, movement: movement{speed: 1}}, bitStreamLane{dir: MoveUp, movement: movement{speed: 1}}),
+			[]Coordinate{{0, 1}, {1, 2}},
+			[]Coordinate{{0, 3}, {1, 0}},
+		},
+		"pattern repeats": {
+			newLaneBitStream(false, bitStreamLane{dir: MoveRight, movement: movement{speed: 1}}, bitStreamLane{dir: MoveLeft, movement: movement{speed: 1}}, bitStreamLane{dir: MoveRight, movement: movement{speed: 0}}),
+			[]Coordinate{{5, 0}, {5, 1}, {5, 2}, {5, 3}},
+			[]Coordinate{{7, 0}, {3, 1}, {5, 2}, {7, 3}},
+		},
+		"column pattern repeats": {
+			newLaneBitStream(true, bitStreamLane{dir: MoveDown, movement: movement{speed: 1}}, bitStreamLane{dir: MoveUp, movement: movement{speed: 1}}),
+			[]Coordinate{{8, 1}, {9, 2}},
+			[]Coordinate{{8, 3}, {9, 0}},
+		},
+		"falls off the edge": {
+			newLaneBitStream(false, bitStreamLane{dir: MoveLeft, movement: movement{speed: 1}}),
+			[]Coordinate{{0, 0}},
+			[]Coordinate{},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			stream := newBitStream(&level, nil, testRand(), SystemClock)
+			for _, c := range tc.from {
+				stream.grid.set(c, marked)
+			}
+			for i := 0; i < 2; i++ {
+				tc.updater.Tick(&stream)
+			}
+			found := []Coordinate{}
+			for x := 0; x < level.Width; x++ {
+				for y := 0; y < level.Height; y++ {
+					if c := (Coordinate{x, y}); stream.At(c) == marked {
+						found = append(found, c)
+					}
+				}
+			}
+			assert.ElementsMatch(t, tc.to, found)
+		})
+	}
+}
+
+func TestShiftBitStream(t *testing.T) {
+	level := GetLevel(Tutorial)
+	level.Width, level.Height = 10, 6
+	level.BitStreamChance = 1
+	level.Walls = []Wall{{X: 7, Y: 1, Width: 1, Height: 1}}
+	marked := Bits{Hidden: BitTypeOne, Value: -1} // no real bit has this value
+
+	for dir := MoveUp; dir <= MoveDownRight; dir++ {
+		t.Run(directionNames[dir], func(t *testing.T) {
+			stream := newBitStream(&level, newWalls(&level, testRand()), testRand(), SystemClock)
+			dx, dy := dir.Delta()
+			edge := Coordinate{0, 0}
+			if dx > 0 || dy > 0 {
+				edge = Coordinate{level.Width - 1, level.Height - 1}
+			}
+			stream.grid.set(Coordinate{4, 3}, marked)
+			stream.grid.set(edge, marked)
+			for i := 0; i < 2; i++ {
+				shiftBitStream(dir, &stream)
+			}
+
+			var found []Coordinate
+			for x := 0; x < level.Width; x++ {
+				for y := 0; y < level.Height; y++ {
+					if c := (Coordinate{x, y}); stream.At(c) == marked {
+						found = append(found, c)
+					}
+				}
+			}
+			assert.Equal(t, []Coordinate{{4 + 2*dx, 3 + 2*dy}}, found, "bits falling off the edge are replaced")
+			assert.Equal(t, BitTypeEmpty, stream.At(Coordinate{7, 1}).Hidden, "walls block the stream")
+			assert.Equal(t, Bits{}, stream.At(Coordinate{-1, 0}), "nothing off the map")
+		})
+	}
+}
+
+func benchmarkShift(b *testing.B, width, height int) {
+	level := GetLevel(Level5)
+	level.Width, level.Height = width, height
+	stream := newBitStream(&level, newWalls(&level, testRand()), testRand(), SystemClock)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		shiftBitStream(MoveDownLeft, &stream)
+	}
+}
+
+func BenchmarkShiftBitStream(b *testing.B) {
+	b.Run("100x20", func(b *testing.B) { benchmarkShift(b, 100, 20) })
+	b.Run("400x100", func(b *testing.B) { benchmarkShift(b, 400, 100) })
+}
+
+func BenchmarkTickAnimations(b *testing.B) {
+	level := GetLevel(Level5)
+	stream := newBitStream(&level, newWalls(&level, testRand()), testRand(), SystemClock)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		stream.TickAnimations()
+	}
+}
+
+func BenchmarkDidCollideWithBit(b *testing.B) {
+	level := GetLevel(Level5)
+	stream := newBitStream(&level, newWalls(&level, testRand()), testRand(), SystemClock)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		stream.DidCollideWithBit(&level, Coordinate{i % level.Width, i % level.Height}, RevealedBitHarmful)
+	}
+}
+
+func TestStepTimersStartOverEachPlay(t *testing.T) {
+	level := GetLevel(Tutorial)
+	clock := NewManualClock(time.Unix(0, 0))
+	updater := newLoopingBitStream(bitStreamStep{dir: MoveLeft, delay: 10 * time.Second}, bitStreamStep{dir: MoveUp, delay: 10 * time.Second})
+
+	stream := newBitStream(&level, nil, testRand(), clock)
+	for i := 0; i < 15; i++ {
+		clock.Advance(time.Second)
+		updater.Tick(&stream)
+	}
+	assert.Equal(t, 1, updater.current)
+
+	// playing the level again, even a long time later, starts from the first step rather than skipping ahead
+	clock.Advance(time.Hour)
+	stream = newBitStream(&level, nil, testRand(), clock)
+	clock.Advance(time.Second)
+	updater.Tick(&stream)
+	assert.Equal(t, 0, updater.current)
+}
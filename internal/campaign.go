@@ -0,0 +1,141 @@
+package zen_doctor
+
+// Campaign is the set of levels to play through, and the ways to get from one to the next.
+type Campaign interface {
+	First() Level
+	// Next returns the levels the player can go to after finishing level, or nothing once the campaign is over.
+	Next(level Level, progress Progress) []Branch
+	// Get returns a fresh config for the level, or false if it isn't part of the campaign.
+	Get(level Level) (LevelConfig, bool)
+}
+
+// Progress is what the player has done so far in the campaign, which decides which ways forward are open.
+type Progress struct {
+	Visited   []Level // every level that's been played, in order
+	Collected []Loot  // everything collected along the way
+}
+
+// HasVisited returns true if the level has been played.
+func (p Progress) HasVisited(level Level) bool {
+	for _, visited := range p.Visited {
+		if visited == level {
+			return true
+		}
+	}
+	return false
+}
+
+// HasCollected returns true if anything at least as rare as rarity has been collected.
+func (p Progress) HasCollected(rarity Rarity) bool {
+	for _, loot := range p.Collected {
+		if loot.Rarity >= rarity {
+			return true
+		}
+	}
+	return false
+}
+
+// Branch is a level the player can go to next.
+type Branch struct {
+	Level Level
+	Label string // describes the route, if there's more than one to pick from
+}
+
+// Edge leads from one level of a campaign to another, if its condition is met.
+type Edge struct {
+	To    Level
+	Label string
+	When  func(progress Progress) bool // the edge can always be taken if this is nil
+}
+
+// campaignGraph holds the edges leading out of each level, in the order they are offered to the player.
+type campaignGraph map[Level][]Edge
+
+func (g campaignGraph) next(level Level, progress Progress) []Branch {
+	var branches []Branch
+	for _, edge := range g[level] {
+		if edge.When == nil || edge.When(progress) {
+			branches = append(branches, Branch{Level: edge.To, Label: edge.Label})
+		}
+	}
+	return branches
+}
+
+func collected(rarity Rarity) func(Progress) bool {
+	return func(p Progress) bool {
+		return p.HasCollected(rarity)
+	}
+}
+
+func visited(level Level) func(Progress) bool {
+	return func(p Progress) bool {
+		return p.HasVisited(level)
+	}
+}
+
+// DefaultCampaign is made up of the levels that are built into the game.
+var DefaultCampaign Campaign = builtinCampaign{}
+
+// the routes through the built in levels. The vault is hidden unless something legendary turned up along the way.
+var builtinGraph = campaignGraph{
+	Tutorial: {{To: Level1}},
+	Level1:   {{To: Level2}},
+	Level2:   {{To: Level3}},
+	Level3: {
+		{To: Mainframe1, Label: "Break into the mainframe"},
+		{To: Vault, Label: "Follow the legendary signal", When: collected(Legendary)},
+	},
+	Mainframe1: {{To: Level4}},
+	Vault:      {{To: Level4}},
+	Level4:     {{To: Level5}},
+	Level5:     {{To: Mainframe2}},
+}
+
+type builtinCampaign struct{}
+
+func (builtinCampaign) First() Level {
+	return Tutorial
+}
+
+func (builtinCampaign) Next(level Level, progress Progress) []Branch {
+	return builtinGraph.next(level, progress)
+}
+
+func (builtinCampaign) Get(level Level) (LevelConfig, bool) {
+	if !level.IsValid() {
+		return LevelConfig{}, false
+	}
+	return GetLevel(level), true
+}
+
+// fileCampaign plays levels that were loaded from files. Unless the campaign file says otherwise, they're played in
+// the order of their file names.
+type fileCampaign struct {
+	levels []levelSource
+	start  Level
+	graph  campaignGraph
+}
+
+func (c *fileCampaign) First() Level {
+	return c.start
+}
+
+func (c *fileCampaign) Next(level Level, progress Progress) []Branch {
+	if _, ok := c.graph[level]; ok {
+		return c.graph.next(level, progress)
+	}
+	if level < 0 || int(level)+1 >= len(c.levels) {
+		return nil
+	}
+	return []Branch{{Level: level + 1}}
+}
+
+func (c *fileCampaign) Get(level Level) (LevelConfig, bool) {
+	if level < 0 || int(level) >= len(c.levels) {
+		return LevelConfig{}, false
+	}
+	// every level was already parsed once when the campaign was loaded, so this can't fail.
+	l, _ := c.levels[level].parse()
+	l.Level = level
+	return l, true
+}
@@ -0,0 +1,133 @@
+package zen_doctor
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// A campaign file sits next to the level files, and lays out the routes between them. Levels are named by their file
+// name, without the extension. Levels that aren't in Edges carry on to the next file, and an empty list ends the
+// campaign there. For example:
+//
+//   Start: 01-warmup
+//   Edges:
+//     02-crossfire:
+//       - To: 04-the-long-way
+//         Label: Take the long way round
+//       - To: 03-vault
+//         Label: Follow the legendary signal
+//         Requires: Legendary
+//     03-vault: []
+
+// the name of the campaign file, without the extension
+const campaignFileName = "campaign"
+
+type campaignFile struct {
+	Start string
+	Edges map[string][]edgeSpec
+}
+
+type edgeSpec struct {
+	To       string
+	Label    string
+	Requires *Rarity // the player must have collected something at least this rare
+	After    string  // the player must have played this level
+}
+
+// loadGraph reads the campaign file in dir, if there is one.
+func (c *fileCampaign) loadGraph(dir string) error {
+	var path string
+	for _, ext := range []string{".json", ".yaml", ".yml"} {
+		candidate := filepath.Join(dir, campaignFileName+ext)
+		if _, err := os.Stat(candidate); err == nil {
+			path = candidate
+			break
+		}
+	}
+	if path == "" {
+		return nil
+	}
+	src, err := readLevelSource(path)
+	if err != nil {
+		return err
+	}
+	if err := c.parseGraph(src); err != nil {
+		return errors.Wrapf(err, "loading campaign %s", path)
+	}
+	return nil
+}
+
+func (c *fileCampaign) parseGraph(src levelSource) error {
+	data, err := toJSON(src.path, src.data)
+	if err != nil {
+		return err
+	}
+	var file campaignFile
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return err
+	}
+
+	if file.Start != "" {
+		if c.start, err = c.find(file.Start); err != nil {
+			return errors.Wrap(err, "Start")
+		}
+	}
+	c.graph = make(campaignGraph)
+	for from, specs := range file.Edges {
+		level, err := c.find(from)
+		if err != nil {
+			return errors.Wrap(err, "Edges")
+		}
+		edges := make([]Edge, 0, len(specs))
+		for _, spec := range specs {
+			edge := Edge{Label: spec.Label}
+			if edge.To, err = c.find(spec.To); err != nil {
+				return errors.Wrapf(err, "Edges.%s", from)
+			}
+			var conditions []func(Progress) bool
+			if spec.Requires != nil {
+				conditions = append(conditions, collected(*spec.Requires))
+			}
+			if spec.After != "" {
+				after, err := c.find(spec.After)
+				if err != nil {
+					return errors.Wrapf(err, "Edges.%s", from)
+				}
+				conditions = append(conditions, visited(after))
+			}
+			if len(conditions) > 0 {
+				edge.When = func(p Progress) bool {
+					for _, condition := range conditions {
+						if !condition(p) {
+							return false
+						}
+					}
+					return true
+				}
+			}
+			edges = append(edges, edge)
+		}
+		c.graph[level] = edges
+	}
+	return nil
+}
+
+// find returns the level loaded from the file with the given name.
+func (c *fileCampaign) find(name string) (Level, error) {
+	names := make([]string, 0, len(c.levels))
+	for i, src := range c.levels {
+		base := strings.TrimSuffix(filepath.Base(src.path), filepath.Ext(src.path))
+		if strings.EqualFold(base, name) {
+			return Level(i), nil
+		}
+		names = append(names, base)
+	}
+	return 0, errors.Errorf("unknown level %q, expected one of %s", name, strings.Join(names, ", "))
+}
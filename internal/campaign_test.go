@@ -0,0 +1,77 @@
+package zen_doctor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuiltinCampaign(t *testing.T) {
+	// every route leads somewhere real, and the main route goes through every level except the hidden one
+	for from, edges := range builtinGraph {
+		assert.True(t, from.IsValid(), "edges from %v", from)
+		for _, edge := range edges {
+			assert.True(t, edge.To.IsValid(), "edge from %v to %v", from, edge.To)
+		}
+	}
+	var route []Level
+	for level := DefaultCampaign.First(); ; {
+		route = append(route, level)
+		next := DefaultCampaign.Next(level, Progress{Visited: route})
+		if len(next) == 0 {
+			break
+		}
+		require.Len(t, next, 1, "only one way forward from %v without any legendary loot", level)
+		level = next[0].Level
+	}
+	assert.Equal(t, campaign, route)
+
+	// the vault opens up once something legendary has been found
+	next := DefaultCampaign.Next(Level3, Progress{Collected: []Loot{{Kind: LootData, Rarity: Legendary}}})
+	require.Len(t, next, 2)
+	assert.Equal(t, Mainframe1, next[0].Level)
+	assert.Equal(t, Vault, next[1].Level)
+}
+
+func TestCampaignFile(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.json", "b.json", "c.json", "d.json"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(`{}`), 0644))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "campaign.yaml"), []byte(`
+Start: b
+Edges:
+  b:
+    - To: d
+    - To: a
+      Requires: Epic
+    - To: c
+      After: a
+  d: []
+`), 0644))
+
+	campaign, err := LoadCampaign(dir)
+	require.NoError(t, err)
+	assert.Equal(t, Level(1), campaign.First())
+
+	targets := func(progress Progress) []Level {
+		var levels []Level
+		for _, branch := range campaign.Next(1, progress) {
+			levels = append(levels, branch.Level)
+		}
+		return levels
+	}
+	assert.Equal(t, []Level{3}, targets(Progress{}))
+	assert.Equal(t, []Level{3, 0}, targets(Progress{Collected: []Loot{{Rarity: Legendary}}}))
+	assert.Equal(t, []Level{3, 2}, targets(Progress{Visited: []Level{0}}))
+	assert.Empty(t, campaign.Next(3, Progress{}), "an empty list ends the campaign")
+	assert.Equal(t, []Branch{{Level: 1}}, campaign.Next(0, Progress{}), "levels without edges go to the next file")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "campaign.yaml"), []byte(`Edges: {b: [{To: e}]}`), 0644))
+	_, err = LoadCampaign(dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `Edges.b: unknown level "e"`)
+}
@@ -0,0 +1,356 @@
+package zen_doctor
+
+import (
+	"bufio"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// A choreography is a little script for how the bit stream moves, so new patterns can be written without any Go.
+// Each line is one step, and the whole thing starts over once it gets to the end:
+//
+//   down 10s           # move down for 10 seconds
+//   left 5s x2         # move left twice as fast for 5 seconds
+//   pause 3s           # stop moving for 3 seconds
+//   repeat 3 {         # do the steps inside 3 times
+//     downleft 2s x0.5 # at half speed
+//     downright 2s
+//   }
+//   random {           # do one of the steps inside, picked at random each time
+//     up 5s
+//     { right 2s; left 2s }
+//   }
+//
+// Directions are the same as in level files, steps can also be split up with ";", and "#" starts a comment.
+
+// the most times a repeat can go around, and the most steps one run through the whole script can turn into once the
+// repeats are unrolled - so a typo can't eat all the memory
+const (
+	maxChoreographyRepeats = 1000
+	maxChoreographySteps   = 10000
+)
+
+// ParseChoreography compiles a choreography script into an updater for the bit stream.
+func ParseChoreography(script string) (BitStreamUpdater, error) {
+	tokens, err := tokenizeChoreography(script)
+	if err != nil {
+		return nil, err
+	}
+	p := &choreographyParser{tokens: tokens}
+	program, err := p.sequence(false)
+	if err != nil {
+		return nil, err
+	}
+	if len(program) == 0 {
+		return nil, errors.New("choreography has no steps")
+	}
+	if program.size() > maxChoreographySteps {
+		return nil, errors.Errorf("choreography has more than %d steps", maxChoreographySteps)
+	}
+	return newChoreography(program), nil
+}
+
+// choreographyNode is a part of the script that turns into steps for the bit stream.
+type choreographyNode interface {
+	// steps returns the steps for one run through this part of the script. Random choices are made again each time.
+	steps(rng *rand.Rand) []choreographyStep
+	// size is the most steps one run through this part of the script can turn into. It stops counting a little past
+	// maxChoreographySteps, so nested repeats can't overflow.
+	size() int
+}
+
+// capSize stops counting steps once there are already too many.
+func capSize(n int) int {
+	if n > maxChoreographySteps {
+		return maxChoreographySteps + 1
+	}
+	return n
+}
+
+type choreographyStep struct {
+	dir      Direction
+	duration time.Duration
+	speed    float32 // how many cells the bit stream moves each tick - it stays still at 0
+}
+
+func (s choreographyStep) steps(_ *rand.Rand) []choreographyStep {
+	return []choreographyStep{s}
+}
+
+func (s choreographyStep) size() int {
+	return 1
+}
+
+type choreographySequence []choreographyNode
+
+func (s choreographySequence) steps(rng *rand.Rand) []choreographyStep {
+	var steps []choreographyStep
+	for _, node := range s {
+		steps = append(steps, node.steps(rng)...)
+	}
+	return steps
+}
+
+func (s choreographySequence) size() int {
+	total := 0
+	for _, node := range s {
+		total = capSize(total + node.size())
+	}
+	return total
+}
+
+type choreographyRepeat struct {
+	times int
+	body  choreographySequence
+}
+
+func (r choreographyRepeat) steps(rng *rand.Rand) []choreographyStep {
+	var steps []choreographyStep
+	for i := 0; i < r.times; i++ {
+		steps = append(steps, r.body.steps(rng)...)
+	}
+	return steps
+}
+
+func (r choreographyRepeat) size() int {
+	return capSize(r.times * r.body.size())
+}
+
+type choreographyRandom []choreographyNode
+
+func (r choreographyRandom) steps(rng *rand.Rand) []choreographyStep {
+	return r[rng.Intn(len(r))].steps(rng)
+}
+
+func (r choreographyRandom) size() int {
+	most := 0
+	for _, node := range r {
+		if n := node.size(); n > most {
+			most = n
+		}
+	}
+	return most
+}
+
+// choreography plays a script on the bit stream, going back to the start of the script once it runs out of steps.
+type choreography struct {
+	program  choreographySequence
+	upcoming []choreographyStep
+	current  choreographyStep
+	started  time.Time
+	movement movement
+}
+
+func newChoreography(program choreographySequence) *choreography {
+	return &choreography{program: program}
+}
+
+func (c *choreography) Tick(stream *BitStream) {
+	now := stream.clock.Now()
+	if c.started.Before(stream.started) {
+		// a new play of the level starts over from the top of the script
+		c.upcoming = nil
+		c.started = time.Time{}
+	}
+	if c.started.IsZero() || now.Sub(c.started) > c.current.duration {
+		if len(c.upcoming) == 0 {
+			c.upcoming = c.program.steps(stream.rng)
+		}
+		c.current, c.upcoming = c.upcoming[0], c.upcoming[1:]
+		c.started = now
+	}
+	c.movement.speed = c.current.speed
+	for steps := c.movement.tick(); steps > 0; steps-- {
+		shiftBitStream(c.current.dir, stream)
+	}
+}
+
+type choreographyToken struct {
+	text string
+	line int
+}
+
+func tokenizeChoreography(script string) ([]choreographyToken, error) {
+	var tokens []choreographyToken
+	scanner := bufio.NewScanner(strings.NewReader(script))
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if i := strings.IndexByte(text, '#'); i >= 0 {
+			text = text[:i]
+		}
+		// braces and semicolons don't need spaces around them
+		for _, punctuation := range []string{"{", "}", ";"} {
+			text = strings.ReplaceAll(text, punctuation, " "+punctuation+" ")
+		}
+		fields := strings.Fields(text)
+		for _, field := range fields {
+			tokens = append(tokens, choreographyToken{text: field, line: line})
+		}
+		if len(fields) > 0 {
+			tokens = append(tokens, choreographyToken{text: ";", line: line})
+		}
+	}
+	return tokens, scanner.Err()
+}
+
+type choreographyParser struct {
+	tokens []choreographyToken
+	pos    int
+}
+
+func (p *choreographyParser) peek() (choreographyToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return choreographyToken{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *choreographyParser) next() (choreographyToken, bool) {
+	token, ok := p.peek()
+	if ok {
+		p.pos++
+	}
+	return token, ok
+}
+
+// line is for error messages, and says where the parser has got to.
+func (p *choreographyParser) line() int {
+	if len(p.tokens) == 0 {
+		return 1
+	}
+	if p.pos >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1].line
+	}
+	return p.tokens[p.pos].line
+}
+
+func (p *choreographyParser) errorf(format string, args ...interface{}) error {
+	return errors.Errorf("line %d: "+format, append([]interface{}{p.line()}, args...)...)
+}
+
+// sequence reads steps up to the end of the script, or the closing brace of a block.
+func (p *choreographyParser) sequence(inBlock bool) (choreographySequence, error) {
+	var seq choreographySequence
+	for {
+		token, ok := p.peek()
+		switch {
+		case !ok && inBlock:
+			return nil, p.errorf("missing }")
+		case !ok:
+			return seq, nil
+		case token.text == ";":
+			p.pos++
+		case token.text == "}" && inBlock:
+			p.pos++
+			return seq, nil
+		case token.text == "}":
+			return nil, p.errorf("unexpected }")
+		default:
+			node, err := p.statement()
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, node)
+		}
+	}
+}
+
+func (p *choreographyParser) block() (choreographySequence, error) {
+	// the brace can go on the next line
+	for token, ok := p.peek(); ok && token.text == ";"; token, ok = p.peek() {
+		p.pos++
+	}
+	if token, ok := p.next(); !ok || token.text != "{" {
+		return nil, p.errorf("expected {")
+	}
+	seq, err := p.sequence(true)
+	if err != nil {
+		return nil, err
+	}
+	if len(seq) == 0 {
+		return nil, p.errorf("empty block")
+	}
+	return seq, nil
+}
+
+func (p *choreographyParser) statement() (choreographyNode, error) {
+	token, _ := p.next()
+	switch strings.ToLower(token.text) {
+	case "{":
+		p.pos--
+		return p.block()
+	case "pause":
+		d, err := p.duration()
+		if err != nil {
+			return nil, err
+		}
+		return choreographyStep{duration: d}, nil
+	case "repeat":
+		times, err := p.repeats()
+		if err != nil {
+			return nil, err
+		}
+		body, err := p.block()
+		if err != nil {
+			return nil, err
+		}
+		repeat := choreographyRepeat{times: times, body: body}
+		if repeat.size() > maxChoreographySteps {
+			return nil, errors.Errorf("line %d: repeat makes more than %d steps", token.line, maxChoreographySteps)
+		}
+		return repeat, nil
+	case "random":
+		choices, err := p.block()
+		if err != nil {
+			return nil, err
+		}
+		return choreographyRandom(choices), nil
+	}
+
+	var dir Direction
+	if err := dir.UnmarshalText([]byte(token.text)); err != nil {
+		return nil, errors.Errorf("line %d: expected a direction, pause, repeat or random, got %q", token.line, token.text)
+	}
+	d, err := p.duration()
+	if err != nil {
+		return nil, err
+	}
+	step := choreographyStep{dir: dir, duration: d, speed: 1}
+	if token, ok := p.peek(); ok && strings.HasPrefix(strings.ToLower(token.text), "x") {
+		p.pos++
+		speed, err := strconv.ParseFloat(token.text[1:], 32)
+		if err != nil || speed <= 0 {
+			return nil, errors.Errorf("line %d: expected a speed like x2 or x0.5, got %q", token.line, token.text)
+		}
+		step.speed = float32(speed)
+	}
+	return step, nil
+}
+
+func (p *choreographyParser) duration() (time.Duration, error) {
+	token, ok := p.next()
+	if !ok || token.text == ";" {
+		return 0, p.errorf("expected a duration like 10s")
+	}
+	d, err := time.ParseDuration(token.text)
+	if err != nil || d <= 0 {
+		return 0, errors.Errorf("line %d: expected a duration like 10s, got %q", token.line, token.text)
+	}
+	return d, nil
+}
+
+func (p *choreographyParser) repeats() (int, error) {
+	token, ok := p.next()
+	if !ok {
+		return 0, p.errorf("expected how many times to repeat")
+	}
+	times, err := strconv.Atoi(token.text)
+	if err != nil || times < 1 || times > maxChoreographyRepeats {
+		return 0, errors.Errorf("line %d: expected how many times to repeat, from 1 to %d, got %q", token.line, maxChoreographyRepeats, token.text)
+	}
+	return times, nil
+}
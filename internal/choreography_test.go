@@ -0,0 +1,115 @@
+package zen_doctor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseChoreography(t *testing.T) {
+	updater, err := ParseChoreography(`
+down 10s           # comments are ignored
+left 5s x2; pause 3s
+repeat 2
+{
+  downleft 2s x0.5
+}
+random { up 5s }
+`)
+	require.NoError(t, err)
+	c := updater.(*choreography)
+	assert.Equal(t, []choreographyStep{
+		{dir: MoveDown, duration: 10 * time.Second, speed: 1},
+		{dir: MoveLeft, duration: 5 * time.Second, speed: 2},
+		{duration: 3 * time.Second},
+		{dir: MoveDownLeft, duration: 2 * time.Second, speed: 0.5},
+		{dir: MoveDownLeft, duration: 2 * time.Second, speed: 0.5},
+		{dir: MoveUp, duration: 5 * time.Second, speed: 1},
+	}, c.program.steps(testRand()))
+}
+
+func TestChoreographyRandom(t *testing.T) {
+	updater, err := ParseChoreography("random { left 1s; { right 1s; up 1s } }")
+	require.NoError(t, err)
+	rng := testRand()
+	seen := make(map[int]bool)
+	for i := 0; i < 100; i++ {
+		seen[len(updater.(*choreography).program.steps(rng))] = true
+	}
+	assert.Equal(t, map[int]bool{1: true, 2: true}, seen, "both choices get picked")
+}
+
+func TestChoreographyErrors(t *testing.T) {
+	tests := map[string]struct {
+		script string
+		want   string
+	}{
+		"empty":            {"# nothing here", "choreography has no steps"},
+		"unknown step":     {"down 1s\nsideways 1s", `line 2: expected a direction, pause, repeat or random, got "sideways"`},
+		"missing duration": {"down", "line 1: expected a duration like 10s"},
+		"bad duration":     {"pause soon", `line 1: expected a duration like 10s, got "soon"`},
+		"bad speed":        {"down 1s x0", `line 1: expected a speed like x2 or x0.5, got "x0"`},
+		"bad repeat":       {"repeat lots { down 1s }", `line 1: expected how many times to repeat`},
+		"too many steps":   {"down 1s\nrepeat 1000 { repeat 1000 { repeat 1000 { down 1s } } }", "line 2: repeat makes more than 10000 steps"},
+		"too long":         {"repeat 1000 { down 1s; up 1s; left 1s; right 1s; pause 1s }\nrepeat 1000 { down 1s; up 1s; left 1s; right 1s; pause 1s }\ndown 1s", "choreography has more than 10000 steps"},
+		"missing brace":    {"random {\n down 1s", "line 2: missing }"},
+		"extra brace":      {"down 1s }", "line 1: unexpected }"},
+		"empty block":      {"random { }", "line 1: empty block"},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseChoreography(tc.script)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.want)
+		})
+	}
+}
+
+func TestChoreographySpeed(t *testing.T) {
+	level := GetLevel(Tutorial)
+	tests := map[string]struct {
+		script string
+		moved  int
+	}{
+		"normal": {"right 1h", 4},
+		"fast":   {"right 1h x2", 8},
+		"slow":   {"right 1h x0.5", 2},
+		"paused": {"pause 1h", 0},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			updater, err := ParseChoreography(tc.script)
+			require.NoError(t, err)
+			stream := newBitStream(&level, nil, testRand(), SystemClock)
+			marked := Bits{Hidden: BitTypeOne, Value: -1}
+			stream.grid.set(Coordinate{0, 0}, marked)
+			for i := 0; i < 4; i++ {
+				updater.Tick(&stream)
+			}
+			assert.Equal(t, marked, stream.At(Coordinate{tc.moved, 0}))
+		})
+	}
+}
+
+func TestChoreographyStartsOverEachPlay(t *testing.T) {
+	level := GetLevel(Tutorial)
+	clock := NewManualClock(time.Unix(0, 0))
+	updater, err := ParseChoreography("left 10s; up 10s")
+	require.NoError(t, err)
+	c := updater.(*choreography)
+
+	stream := newBitStream(&level, nil, testRand(), clock)
+	for i := 0; i < 15; i++ {
+		clock.Advance(time.Second)
+		c.Tick(&stream)
+	}
+	assert.Equal(t, MoveUp, c.current.dir)
+
+	clock.Advance(time.Hour)
+	stream = newBitStream(&level, nil, testRand(), clock)
+	clock.Advance(time.Second)
+	c.Tick(&stream)
+	assert.Equal(t, MoveLeft, c.current.dir)
+}
@@ -0,0 +1,106 @@
+package zen_doctor
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the game what time it is. Nothing in the game looks at the time any other way, so a game can be played
+// on a clock that is stepped by hand, and come out the same every time.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the time on the wall, for playing in real time.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// GameClock is the time in the game. It follows another clock, usually the one on the wall, but only while it's
+// running - and as fast or as slow as it's set to go.
+type GameClock struct {
+	mu     sync.Mutex
+	source Clock
+	now    time.Time // the game's time, as of the last time the source was looked at
+	last   time.Time // the source's time, when it was last looked at
+	paused bool
+	scale  float64
+}
+
+func NewGameClock(source Clock) *GameClock {
+	now := source.Now()
+	return &GameClock{source: source, now: now, last: now, scale: 1}
+}
+
+func (c *GameClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.catchUp()
+	return c.now
+}
+
+// catchUp moves the game's time along by however long the source has run since it was last looked at.
+func (c *GameClock) catchUp() {
+	now := c.source.Now()
+	if !c.paused {
+		c.now = c.now.Add(time.Duration(float64(now.Sub(c.last)) * c.scale))
+	}
+	c.last = now
+}
+
+// Pause stops the game's time until it's resumed.
+func (c *GameClock) Pause() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.catchUp()
+	c.paused = true
+}
+
+// Resume starts the game's time again, from where it was paused.
+func (c *GameClock) Resume() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.catchUp()
+	c.paused = false
+}
+
+func (c *GameClock) IsPaused() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.paused
+}
+
+// SetScale changes how fast the game's time goes - 2 is twice as fast as the source, and 0.5 half as fast.
+func (c *GameClock) SetScale(scale float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.catchUp()
+	c.scale = scale
+}
+
+// ManualClock only moves when it's told to. It's safe to read while another goroutine moves it along.
+type ManualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewManualClock(start time.Time) *ManualClock {
+	return &ManualClock{now: start}
+}
+
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d.
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
@@ -0,0 +1,37 @@
+package zen_doctor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGameClock(t *testing.T) {
+	wall := NewManualClock(time.Unix(0, 0))
+	c := NewGameClock(wall)
+	start := c.Now()
+
+	wall.Advance(time.Minute)
+	assert.Equal(t, time.Minute, c.Now().Sub(start))
+
+	c.Pause()
+	assert.True(t, c.IsPaused())
+	wall.Advance(time.Hour)
+	assert.Equal(t, time.Minute, c.Now().Sub(start), "time stands still while paused")
+
+	c.Resume()
+	wall.Advance(time.Second)
+	assert.Equal(t, time.Minute+time.Second, c.Now().Sub(start), "and picks up where it left off")
+
+	c.SetScale(0.5)
+	wall.Advance(time.Minute)
+	assert.Equal(t, time.Minute+31*time.Second, c.Now().Sub(start))
+
+	c.Pause()
+	c.SetScale(2)
+	wall.Advance(time.Minute)
+	c.Resume()
+	wall.Advance(time.Second)
+	assert.Equal(t, time.Minute+33*time.Second, c.Now().Sub(start), "changing speed doesn't unpause it")
+}
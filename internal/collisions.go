@@ -0,0 +1,95 @@
+package zen_doctor
+
+// Collisions are swept: rather than only checking where the bit stream leaves things, the player's cell is checked each
+// time the stream moves a step, and each time the player moves. A bit that moves through the player still counts, even
+// when the stream moves more than one cell at a time. Bits only move with the stream, so a bit that swaps places with
+// the player is counted when the player steps onto it - and a bit that lands where the player has just left missed.
+
+// collision is a bit that ran into the player.
+type collision struct {
+	at   Coordinate // where they met
+	bits Bits
+}
+
+type sweep struct {
+	at       Coordinate      // where the player is
+	seen     map[uint64]bool // bits that have already been on the player since the bit stream last moved
+	hits     []collision     // in the order the bits reached the player, so the same game always plays out the same way
+	reported int             // how many of the hits have been reported
+
+	// the bit that was on the player when the sweep started. It doesn't count while it sits there until the bit stream
+	// has finished moving, since it might just be on its way through.
+	resting   uint64
+	restingAt Coordinate
+}
+
+// startSweep forgets about the bits the player has met, and starts keeping track from c. Whatever is already on c has
+// been dealt with, so it's only counted if the bit stream doesn't move it.
+func (bs *BitStream) startSweep(c Coordinate) {
+	bs.sweep = sweep{
+		at:        c,
+		seen:      make(map[uint64]bool),
+		resting:   bs.grid.at(c).id,
+		restingAt: c,
+	}
+}
+
+// watch moves the sweep along to c, where the player is now, and counts whatever bit is on it.
+func (bs *BitStream) watch(c Coordinate) {
+	if bs.sweep.seen == nil {
+		bs.startSweep(c)
+	}
+	bs.sweep.at = c
+	bs.sweepCell(c, true)
+}
+
+// sweepAll counts the bit on the player's cell. It's called each time the bit stream moves.
+func (bs *BitStream) sweepAll() {
+	if bs.sweep.seen != nil {
+		bs.sweepCell(bs.sweep.at, false)
+	}
+}
+
+// sweepCell counts the bit on c, unless it has already been counted. The resting bit is only counted when settled.
+func (bs *BitStream) sweepCell(c Coordinate, settled bool) {
+	b := bs.grid.at(c)
+	if b.Revealed == RevealedBitBenign || b.spent || bs.sweep.seen[b.id] {
+		return
+	}
+	if !settled && b.id == bs.sweep.resting && c == bs.sweep.restingAt {
+		return
+	}
+	bs.sweep.seen[b.id] = true
+	bs.sweep.hits = append(bs.sweep.hits, collision{at: c, bits: b})
+}
+
+// collisions returns the bits that have run into the player since the last time it was called.
+func (bs *BitStream) collisions() []collision {
+	hits := bs.sweep.hits[bs.sweep.reported:]
+	bs.sweep.reported = len(bs.sweep.hits)
+	return hits
+}
+
+// find returns the bit with the given id, wherever it has got to, or nil if it has left the bit stream.
+func (bs *BitStream) find(id uint64) *Bits {
+	for i := range bs.grid.cells {
+		if bs.grid.cells[i].id == id {
+			return &bs.grid.cells[i]
+		}
+	}
+	return nil
+}
+
+// neutralize makes a bit harmless, wherever it has got to.
+func (bs *BitStream) neutralize(id uint64) {
+	if b := bs.find(id); b != nil {
+		b.Revealed = RevealedBitBenign
+	}
+}
+
+// spend uses up a bad bit once it has hit the player, so it can't hit them again.
+func (bs *BitStream) spend(id uint64) {
+	if b := bs.find(id); b != nil {
+		b.spent = true
+	}
+}
@@ -0,0 +1,161 @@
+package zen_doctor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func collisionLevel(speed float32) LevelConfig {
+	level := GetLevel(Tutorial)
+	level.Walls = nil
+	level.BitStreamChance = 0
+	level.MovementThreat = 0
+	level.ThreatByRarity = map[Rarity]float32{Legendary: 10}
+	level.HitInvulnerability = 0
+	level.Updater = newLaneBitStream(false, bitStreamLane{dir: MoveRight, movement: movement{speed: speed}})
+	return level
+}
+
+// the bits put in by hand have ids well past any the bit stream has made
+func badBit(id uint64) Bits {
+	return Bits{Hidden: BitTypeOne, Revealed: RevealedBitHarmful, Value: Legendary, id: id}
+}
+
+func TestFastBitsCantSkipThePlayer(t *testing.T) {
+	s := NewGameStateWithPlayerAt(Coordinate{4, 5}, collisionLevel(3), CompatibilityAscii, testRand(), SystemClock)
+	require.Equal(t, Coordinate{4, 5}, s.player.Location)
+	s.bits.grid.set(Coordinate{2, 5}, badBit(1<<40))
+	s.bits.grid.set(Coordinate{3, 5}, badBit(1<<40+1))
+
+	s.bits.generator.Next(s.rng)
+	s.level.Updater.Tick(&s.bits)
+	collisions := s.tickCollisions()
+
+	assert.Equal(t, BitTypeEmpty, s.bits.At(Coordinate{4, 5}).Hidden, "both bits went straight past")
+	assert.Len(t, collisions, 2, "both bits count")
+	assert.Equal(t, float32(20), s.player.Threat)
+}
+
+func TestDodgingBits(t *testing.T) {
+	tests := map[string]struct {
+		move   Direction
+		threat float32
+	}{
+		"sidestep":            {MoveUp, 0},
+		"step out of the way": {MoveRight, 0},
+		"step into it":        {MoveLeft, 10},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := NewGameStateWithPlayerAt(Coordinate{4, 5}, collisionLevel(1), CompatibilityAscii, testRand(), SystemClock)
+			s.bits.grid.set(Coordinate{3, 5}, badBit(1<<40))
+
+			s.MovePlayer(tc.move)
+			s.TickBitStream()
+			assert.Equal(t, BitTypeOne, s.bits.At(Coordinate{4, 5}).Hidden, "the bit moved onto the cell the player was on")
+			assert.Equal(t, tc.threat, s.player.Threat)
+		})
+	}
+}
+
+func TestCollisionsOnlyCountOnce(t *testing.T) {
+	s := NewGameStateWithPlayerAt(Coordinate{4, 5}, collisionLevel(1), CompatibilityAscii, testRand(), SystemClock)
+	s.bits.grid.set(Coordinate{5, 5}, badBit(1<<40))
+
+	// the player steps onto the bit, which then moves on with them
+	s.MovePlayer(MoveRight)
+	assert.Equal(t, float32(10), s.player.Threat)
+	s.MovePlayer(MoveRight)
+	assert.Equal(t, float32(10), s.player.Threat, "the bit isn't there any more")
+	s.TickBitStream()
+	assert.Equal(t, float32(10), s.player.Threat, "the bit crossed the player's path, but has already hit them")
+
+	// once the stream has moved, a bit that catches up with the player counts again
+	s.TickBitStream()
+	assert.Equal(t, float32(10), s.player.Threat)
+	s.bits.grid.set(Coordinate{5, 5}, badBit(1<<40+1))
+	s.TickBitStream()
+	assert.Equal(t, float32(20), s.player.Threat)
+}
+
+func TestHelpfulBitsAreUsedUp(t *testing.T) {
+	s := NewGameStateWithPlayerAt(Coordinate{4, 5}, collisionLevel(2), CompatibilityAscii, testRand(), SystemClock)
+	s.player.Threat = 50
+	s.bits.grid.set(Coordinate{3, 5}, Bits{Hidden: BitTypeOne, Revealed: RevealedBitHelpful, Value: Legendary, id: 1 << 40})
+
+	s.TickBitStream()
+	assert.Equal(t, float32(40), s.player.Threat)
+	assert.Equal(t, RevealedBitBenign, s.bits.At(Coordinate{5, 5}).Revealed, "the bit is neutralized where it ended up")
+}
+
+func TestBadBitsOnlyHitOnce(t *testing.T) {
+	s := NewGameStateWithPlayerAt(Coordinate{4, 5}, collisionLevel(0), CompatibilityAscii, testRand(), SystemClock)
+	s.bits.grid.set(Coordinate{5, 5}, badBit(1<<40))
+
+	s.MovePlayer(MoveRight)
+	assert.Equal(t, float32(10), s.player.Threat)
+	for i := 0; i < 3; i++ {
+		s.TickBitStream()
+	}
+	s.MovePlayer(MoveLeft)
+	s.MovePlayer(MoveRight)
+	assert.Equal(t, float32(10), s.player.Threat, "the bit is spent, even though it's still there")
+	assert.Equal(t, RevealedBitHarmful, s.bits.At(Coordinate{5, 5}).Revealed, "spent bits still look bad")
+}
+
+func TestHitInvulnerability(t *testing.T) {
+	level := collisionLevel(3)
+	level.HitInvulnerability = time.Minute
+	level.HitFlashRate = time.Second
+	s := NewGameStateWithPlayerAt(Coordinate{4, 5}, level, CompatibilityAscii, testRand(), SystemClock)
+	s.bits.grid.set(Coordinate{2, 5}, badBit(1<<40))
+	s.bits.grid.set(Coordinate{3, 5}, badBit(1<<40+1))
+
+	s.TickBitStream()
+	assert.Equal(t, float32(10), s.player.Threat, "the second bit passed through")
+	assert.True(t, s.player.isInvulnerable(time.Now()))
+
+	// once the window is over, bits hit again - including the one that passed through
+	s.player.invulnerableUntil = time.Now()
+	passed := s.bits.At(Coordinate{5, 5})
+	require.Equal(t, uint64(1<<40), passed.id, "the bit behind came onto the player second")
+	require.False(t, passed.spent)
+	s.bits.grid.set(Coordinate{3, 5}, passed)
+	s.TickBitStream()
+	assert.Equal(t, float32(20), s.player.Threat)
+}
+
+func TestHitsComeInTheOrderBitsArrive(t *testing.T) {
+	level := collisionLevel(3)
+	level.HitInvulnerability = time.Minute
+	for i := 0; i < 20; i++ {
+		s := NewGameStateWithPlayerAt(Coordinate{4, 5}, level, CompatibilityAscii, testRand(), SystemClock)
+		s.bits.grid.set(Coordinate{2, 5}, badBit(1<<40))
+		s.bits.grid.set(Coordinate{3, 5}, badBit(1<<40+1))
+
+		s.bits.generator.Next(s.rng)
+		s.level.Updater.Tick(&s.bits)
+		collisions := s.tickCollisions()
+		require.Len(t, collisions, 2)
+		assert.Equal(t, uint64(1<<40+1), collisions[0].bits.id, "the bit in front gets there first")
+		assert.Equal(t, uint64(1<<40), collisions[1].bits.id)
+		assert.True(t, s.bits.find(1<<40+1).spent, "the first bit hits")
+		assert.False(t, s.bits.find(1<<40).spent, "the second passes through")
+	}
+}
+
+func TestPlayerFlashes(t *testing.T) {
+	now := time.Now()
+	p := newPlayer(Coordinate{})
+	assert.False(t, p.isFlashing(now, time.Second), "not hit yet")
+
+	p.hit(now, 4*time.Second)
+	assert.True(t, p.isFlashing(now, time.Second))
+	assert.False(t, p.isFlashing(now.Add(500*time.Millisecond), time.Second))
+	assert.True(t, p.isFlashing(now.Add(1500*time.Millisecond), time.Second))
+	assert.False(t, p.isFlashing(now, 0), "a rate of 0 turns it off")
+	assert.False(t, p.isFlashing(now.Add(4*time.Second), time.Second), "the window is over")
+}
@@ -0,0 +1,415 @@
+package zen_doctor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Brushes paint a layout character onto the map.
+var Brushes = []rune{layoutWall, layoutData, layoutPowerUp, layoutItem, layoutSpawn, layoutExit, layoutEmitter, layoutOpen}
+
+var brushNames = map[rune]string{
+	layoutWall:    "Wall",
+	layoutData:    "Data",
+	layoutPowerUp: "Power up",
+	layoutItem:    "Item",
+	layoutSpawn:   "Spawn",
+	layoutExit:    "Exit",
+	layoutEmitter: "Emitter",
+	layoutOpen:    "Erase",
+}
+
+// editorField is a number in the level config that can be changed from the side panel.
+type editorField struct {
+	Name string
+	Step float64
+	Max  float64 // no limit if 0
+}
+
+var editorFields = []editorField{
+	{Name: "FPS", Step: 0.25},
+	{Name: "BitStreamChance", Step: 0.05, Max: 1},
+	{Name: "GoodBitChance", Step: 0.01, Max: 1},
+	{Name: "BadBitChance", Step: 0.01, Max: 1},
+	{Name: "MaxThreat", Step: 5},
+	{Name: "MovementThreat", Step: 0.05},
+	{Name: "ViewDist", Step: 0.5},
+	{Name: "InitialData", Step: 1},
+	{Name: "DataSpawnRate", Step: 0.001},
+	{Name: "InitialPowerUps", Step: 1},
+	{Name: "PowerUpSpawnRate", Step: 0.001},
+	{Name: "InitialItems", Step: 1},
+	{Name: "ItemSpawnRate", Step: 0.001},
+	{Name: "EnemyCount", Step: 1},
+	{Name: "TrackerCount", Step: 1},
+	{Name: "HunterCount", Step: 1},
+}
+
+// Editor holds a level file while it's being drawn. The map is always saved as a layout, with the rest of the file
+// left the way it was, apart from the numbers that were changed.
+type Editor struct {
+	path     string
+	original []byte                     // the file as it was loaded, so saving can keep everything that wasn't edited
+	fields   map[string]json.RawMessage // the level, with any edits
+	edited   map[string]bool
+	grid     [][]rune
+	level    LevelConfig // the level as it would be played right now
+	view     View
+
+	Cursor   Coordinate
+	Brush    rune
+	Pen      bool // paint every cell the cursor moves over
+	Selected int  // which field is selected in the side panel
+	Status   string
+}
+
+// NewEditor opens a level file for editing, or starts a new one if it doesn't exist yet.
+func NewEditor(path string, mode CompatibilityMode) (*Editor, error) {
+	if !isLevelFile(path) {
+		return nil, errors.Errorf("%s isn't a level file - it should end in .json, .yaml or .yml", path)
+	}
+	e := &Editor{
+		path:   path,
+		fields: make(map[string]json.RawMessage),
+		edited: make(map[string]bool),
+		Brush:  layoutWall,
+		Status: fmt.Sprintf("Editing %s", path),
+	}
+	data, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		e.Status = fmt.Sprintf("New level %s", path)
+	case err != nil:
+		return nil, errors.Wrapf(err, "reading level %s", path)
+	default:
+		e.original = data
+		converted, err := toJSON(path, data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "loading level %s", path)
+		}
+		if err := json.Unmarshal(converted, &e.fields); err != nil {
+			return nil, errors.Wrapf(err, "loading level %s", path)
+		}
+	}
+	level, err := parseLevelJSON(path, e.document(nil))
+	if err != nil {
+		return nil, errors.Wrapf(err, "loading level %s", path)
+	}
+
+	// draw everything that was in the level onto the map, so it all ends up in the layout
+	e.grid = make([][]rune, level.Height)
+	for y := range e.grid {
+		e.grid[y] = []rune(strings.Repeat(string(layoutOpen), level.Width))
+	}
+	for _, wall := range level.Walls {
+		for _, c := range wall.Coordinates() {
+			e.set(c, layoutWall)
+		}
+	}
+	if layout := level.Layout; layout != nil {
+		for c, kind := range layout.LootSpots {
+			e.set(c, map[LootKind]rune{LootData: layoutData, LootPowerUp: layoutPowerUp, LootItem: layoutItem}[kind])
+		}
+		for _, c := range layout.Spawns {
+			e.set(c, layoutSpawn)
+		}
+		for _, c := range layout.Exits {
+			e.set(c, layoutExit)
+		}
+		for _, c := range layout.Emitters {
+			e.set(c, layoutEmitter)
+		}
+	}
+	e.Cursor = Coordinate{level.Width / 2, level.Height / 2}
+	e.view = newView(level.Width, level.Height, mode)
+	e.edited["Layout"] = true
+	e.refresh()
+	return e, nil
+}
+
+func (e *Editor) set(c Coordinate, r rune) {
+	if c.Y >= 0 && c.Y < len(e.grid) && c.X >= 0 && c.X < len(e.grid[c.Y]) {
+		e.grid[c.Y][c.X] = r
+	}
+}
+
+// Level returns the level as it would be played right now.
+func (e *Editor) Level() LevelConfig {
+	return e.level
+}
+
+// layoutText draws the map in the layout file format.
+func (e *Editor) layoutText() string {
+	b := strings.Builder{}
+	for _, row := range e.grid {
+		b.WriteString(string(row))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// document returns the level file as JSON, with layout as the map. The map replaces any walls and size from the file.
+func (e *Editor) document(layout interface{}) []byte {
+	doc := make(map[string]json.RawMessage, len(e.fields)+1)
+	for name, value := range e.fields {
+		doc[name] = value
+	}
+	if layout != nil {
+		for _, name := range []string{"Walls", "Width", "Height"} {
+			delete(doc, name)
+		}
+		doc["Layout"], _ = json.Marshal(layout)
+	}
+	data, _ := json.Marshal(doc)
+	return data
+}
+
+// refresh rebuilds the level after an edit.
+func (e *Editor) refresh() {
+	level, err := parseLevelJSON(e.path, e.document(e.layoutText()))
+	if err != nil {
+		e.Status = err.Error()
+		return
+	}
+	e.level = level
+}
+
+func (e *Editor) MoveCursor(dir Direction) {
+	dx, dy := dir.Delta()
+	next := Coordinate{e.Cursor.X + dx, e.Cursor.Y + dy}
+	if next.X < 0 || next.X >= e.level.Width || next.Y < 0 || next.Y >= e.level.Height {
+		return
+	}
+	e.Cursor = next
+	if e.Pen {
+		e.Paint()
+	}
+}
+
+// Paint draws the current brush at the cursor.
+func (e *Editor) Paint() {
+	e.set(e.Cursor, e.Brush)
+	e.refresh()
+}
+
+func (e *Editor) SetBrush(brush rune) {
+	e.Brush = brush
+	e.Status = fmt.Sprintf("Painting %s", brushNames[brush])
+}
+
+func (e *Editor) TogglePen() {
+	e.Pen = !e.Pen
+	if e.Pen {
+		e.Paint()
+	}
+}
+
+func (e *Editor) SelectField(delta int) {
+	e.Selected = (e.Selected + delta + len(editorFields)) % len(editorFields)
+}
+
+// Adjust changes the selected field by a number of steps.
+func (e *Editor) Adjust(steps int) {
+	field := editorFields[e.Selected]
+	value := reflect.ValueOf(e.level).FieldByName(field.Name)
+	var next interface{}
+	switch value.Kind() {
+	case reflect.Int:
+		v := value.Int() + int64(steps)*int64(field.Step)
+		if v < 0 {
+			v = 0
+		}
+		next = v
+	default:
+		v := value.Float() + float64(steps)*field.Step
+		v = math.Max(0, math.Round(v*10000)/10000) // no floating point fuzz in the level file
+		if field.Max > 0 {
+			v = math.Min(field.Max, v)
+		}
+		next = v
+	}
+	e.fields[field.Name], _ = json.Marshal(next)
+	e.edited[field.Name] = true
+	e.refresh()
+}
+
+// Fields lists the numbers that can be changed from the side panel, with the selected one highlighted.
+func (e *Editor) Fields() string {
+	b := strings.Builder{}
+	for i, field := range editorFields {
+		value := reflect.ValueOf(e.level).FieldByName(field.Name)
+		line := fmt.Sprintf("%-16s %v\n", field.Name, value.Interface())
+		if i == e.Selected {
+			line = WithColor(Green, "> "+line)
+		} else {
+			line = "  " + line
+		}
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
+// BrushNames lists the brushes, next to the number key that picks them.
+func (e *Editor) BrushNames() string {
+	b := strings.Builder{}
+	for i, brush := range Brushes {
+		line := fmt.Sprintf("%d %c %s\n", i+1, brush, brushNames[brush])
+		if brush == e.Brush {
+			line = WithColor(Green, line)
+		}
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
+func (e *Editor) String() string {
+	e.apply()
+	return e.view.String()
+}
+
+// apply draws the map into the view, the same way the game would.
+func (e *Editor) apply() {
+	for y, row := range e.grid {
+		for x, r := range row {
+			c := Coordinate{x, y}
+			cell := Cell{Background: Black, Foreground: DarkGray, Symbol: string(layoutOpen)}
+			switch r {
+			case layoutWall:
+				cell.Foreground, cell.Symbol = Gray, WallSymbol.ForMode(e.view.Mode)
+			case layoutData:
+				cell.Foreground, cell.Symbol = White, e.level.DefaultData.ForMode(e.view.Mode)
+			case layoutPowerUp:
+				cell.Foreground, cell.Symbol = White, e.level.DefaultPowerUp.ForMode(e.view.Mode)
+			case layoutItem:
+				cell.Foreground, cell.Symbol = White, ItemPylon.ForMode(e.view.Mode)
+			case layoutSpawn:
+				cell.Foreground, cell.Symbol = YellowGreen, PlayerSymbol.ForMode(e.view.Mode)
+			case layoutExit:
+				cell.Foreground, cell.Symbol = e.view.exitSymbol()
+			case layoutEmitter:
+				cell.Foreground, cell.Symbol = Green, EmitterSymbol.ForMode(e.view.Mode)
+			}
+			if c.Equals(e.Cursor) {
+				cell.Background = Lavender
+				if e.Pen {
+					cell.Background = Green
+				}
+			}
+			e.view.Data[c] = cell
+		}
+	}
+}
+
+// Campaign returns a campaign with just the level being edited, to try it out.
+func (e *Editor) Campaign() (Campaign, error) {
+	if errs := e.level.Validate(); len(errs) > 0 {
+		return nil, errors.Errorf("level is not valid: %v", errs)
+	}
+	// the map always goes in the file, so it doesn't matter if the layout file on disk is out of date
+	path := strings.TrimSuffix(e.path, filepath.Ext(e.path)) + ".json"
+	return &fileCampaign{levels: []levelSource{{path: path, data: e.document(e.layoutText())}}}, nil
+}
+
+// Save writes the level back to its file. If the layout lives in a file of its own, that gets saved too.
+func (e *Editor) Save() error {
+	layout := e.layoutText()
+	var layoutPath string
+	if err := json.Unmarshal(e.fields["Layout"], &layoutPath); err == nil && !strings.Contains(layoutPath, "\n") {
+		path := layoutPath
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(e.path), path)
+		}
+		if err := os.WriteFile(path, []byte(layout), 0644); err != nil {
+			return errors.Wrapf(err, "saving layout %s", path)
+		}
+	} else {
+		layoutPath = layout
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(e.document(layoutPath), &fields); err != nil {
+		return err
+	}
+	var data []byte
+	var err error
+	if ext := strings.ToLower(filepath.Ext(e.path)); ext == ".yaml" || ext == ".yml" {
+		data, err = e.saveYAML(fields)
+	} else {
+		data, err = json.MarshalIndent(fields, "", "  ")
+	}
+	if err != nil {
+		return errors.Wrapf(err, "saving level %s", e.path)
+	}
+	if err := os.WriteFile(e.path, data, 0644); err != nil {
+		return errors.Wrapf(err, "saving level %s", e.path)
+	}
+	e.original = data
+	e.Status = fmt.Sprintf("Saved %s", e.path)
+	return nil
+}
+
+// saveYAML updates the fields that were edited in the original YAML, so the order and any comments are kept.
+func (e *Editor) saveYAML(fields map[string]json.RawMessage) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(e.original, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+
+	// the layout replaces these
+	for i := 0; i+1 < len(root.Content); {
+		if name := root.Content[i].Value; name == "Walls" || name == "Width" || name == "Height" {
+			root.Content = append(root.Content[:i], root.Content[i+2:]...)
+		} else {
+			i += 2
+		}
+	}
+	for _, field := range append(editorFields, editorField{Name: "Layout"}) {
+		if !e.edited[field.Name] {
+			continue
+		}
+		var value interface{}
+		if err := json.Unmarshal(fields[field.Name], &value); err != nil {
+			return nil, err
+		}
+		encoded, err := yaml.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		var valueDoc yaml.Node
+		if err := yaml.Unmarshal(encoded, &valueDoc); err != nil {
+			return nil, err
+		}
+		node := *valueDoc.Content[0]
+		found := false
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			if root.Content[i].Value == field.Name {
+				root.Content[i+1] = &node
+				found = true
+			}
+		}
+		if !found {
+			root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: field.Name}, &node)
+		}
+	}
+
+	b := bytes.Buffer{}
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,85 @@
+package zen_doctor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEditor(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "level.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`# a tiny test level
+Title: Tiny
+Width: 10
+Height: 5
+FPS: 2
+Walls:
+  - {X: 0, Y: 0, Width: 10, Height: 1}
+`), 0644))
+
+	e, err := NewEditor(path, CompatibilityAscii)
+	require.NoError(t, err)
+	assert.Equal(t, 10, e.Level().Width)
+	assert.Len(t, e.Level().Walls, 1, "walls from the file are part of the map")
+
+	// paint a spawn point and an exit
+	e.Cursor = Coordinate{1, 2}
+	e.SetBrush(layoutSpawn)
+	e.Paint()
+	e.MoveCursor(MoveRight)
+	e.SetBrush(layoutExit)
+	e.TogglePen()
+	e.MoveCursor(MoveRight)
+	require.NotNil(t, e.Level().Layout)
+	assert.Equal(t, []Coordinate{{1, 2}}, e.Level().Layout.Spawns)
+	assert.Equal(t, []Coordinate{{2, 2}, {3, 2}}, e.Level().Layout.Exits)
+
+	// change a number in the side panel
+	e.Selected = 0
+	e.Adjust(2)
+	assert.Equal(t, float32(2.5), e.Level().FPS)
+
+	require.NoError(t, e.Save())
+	saved, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(saved), "# a tiny test level", "comments are kept")
+	assert.NotContains(t, string(saved), "Walls", "walls are in the layout now")
+
+	loaded, err := LoadLevel(path)
+	require.NoError(t, err)
+	assert.Equal(t, "Tiny", loaded.Name())
+	assert.Equal(t, float32(2.5), loaded.FPS)
+	assert.Equal(t, e.Level().Layout, loaded.Layout)
+
+	// the level can be played straight from the editor
+	campaign, err := e.Campaign()
+	require.NoError(t, err)
+	played, ok := campaign.Get(campaign.First())
+	require.True(t, ok)
+	assert.Equal(t, e.Level().Layout, played.Layout)
+}
+
+func TestEditorLayoutFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "level.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"Layout": "map.txt"}`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "map.txt"), []byte("#...\n.@..\n"), 0644))
+
+	e, err := NewEditor(path, CompatibilityAscii)
+	require.NoError(t, err)
+	e.Cursor = Coordinate{3, 1}
+	e.SetBrush(layoutData)
+	e.Paint()
+	require.NoError(t, e.Save())
+
+	layout, err := os.ReadFile(filepath.Join(dir, "map.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "#...\n.@.D\n", string(layout), "the layout file is saved too")
+	level, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"Layout": "map.txt"}`, string(level))
+}
@@ -0,0 +1,64 @@
+package zen_doctor
+
+import (
+	"sort"
+	"time"
+)
+
+// powerUpEffect is a collected power up that is currently affecting the player.
+type powerUpEffect struct {
+	Kind    PowerUpKind
+	Rarity  Rarity
+	Expires time.Time
+}
+
+func (e powerUpEffect) Remaining(now time.Time) time.Duration {
+	if now.After(e.Expires) {
+		return 0
+	}
+	return e.Expires.Sub(now)
+}
+
+// activeEffects holds at most one effect per kind of power up.
+type activeEffects map[PowerUpKind]powerUpEffect
+
+func (a activeEffects) activate(kind PowerUpKind, rarity Rarity, duration time.Duration, now time.Time) {
+	effect := powerUpEffect{
+		Kind:    kind,
+		Rarity:  rarity,
+		Expires: now.Add(duration),
+	}
+	// picking up the same power up again extends the one that is already running
+	if current, ok := a[kind]; ok && current.Expires.After(now) {
+		effect.Expires = current.Expires.Add(duration)
+		if current.Rarity > rarity {
+			effect.Rarity = current.Rarity
+		}
+	}
+	a[kind] = effect
+}
+
+func (a activeEffects) tick(now time.Time) {
+	for kind, effect := range a {
+		if !effect.Expires.After(now) {
+			delete(a, kind)
+		}
+	}
+}
+
+func (a activeEffects) IsActive(kind PowerUpKind) bool {
+	_, ok := a[kind]
+	return ok
+}
+
+// Sorted returns the active effects in a stable order for display.
+func (a activeEffects) Sorted() []powerUpEffect {
+	effects := make([]powerUpEffect, 0, len(a))
+	for _, effect := range a {
+		effects = append(effects, effect)
+	}
+	sort.Slice(effects, func(i, j int) bool {
+		return effects[i].Kind < effects[j].Kind
+	})
+	return effects
+}
@@ -0,0 +1,181 @@
+package zen_doctor
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// generated levels are numbered well clear of the levels in any campaign, so they can't be mixed up.
+const endlessLevel Level = 1000
+
+// the built in campaign ends at this depth, so generated levels pick up the difficulty curve from here.
+const endlessStartDepth = 6
+
+// NewEndlessCampaign plays through a campaign, then keeps going with generated levels that get harder forever.
+// The same seed always generates the same levels.
+func NewEndlessCampaign(base Campaign, seed int64) Campaign {
+	return &endlessCampaign{base: base, seed: seed}
+}
+
+type endlessCampaign struct {
+	base Campaign
+	seed int64
+}
+
+func (c *endlessCampaign) First() Level {
+	return c.base.First()
+}
+
+func (c *endlessCampaign) Next(level Level, progress Progress) []Branch {
+	if level >= endlessLevel {
+		return []Branch{{Level: level + 1}}
+	}
+	if branches := c.base.Next(level, progress); len(branches) > 0 {
+		return branches
+	}
+	return []Branch{{Level: endlessLevel + endlessStartDepth, Label: "Keep going"}}
+}
+
+func (c *endlessCampaign) Get(level Level) (LevelConfig, bool) {
+	if level >= endlessLevel {
+		l := GenerateLevel(c.seed, int(level-endlessLevel))
+		l.Level = level
+		return l, true
+	}
+	return c.base.Get(level)
+}
+
+// GenerateLevel builds the level at the given depth, where depth 5 is about as hard as Level5.
+// Everything that's random about the level comes from the seed, so the same seed and depth always give the same level.
+func GenerateLevel(seed int64, depth int) LevelConfig {
+	rng := rand.New(rand.NewSource(seed*7919 + int64(depth)))
+	n := float64(depth - 5)
+	if n < 0 {
+		n = 0
+	}
+
+	l := defaultLevel()
+	l.Level = endlessLevel + Level(depth)
+	l.Title = fmt.Sprintf("Level %d", depth)
+
+	// the bit stream gets faster and busier, up to the point where it's only just possible to get through
+	l.FPS = float32(difficulty(n, 7, 20, 0.1))
+	l.BitStreamChance = float32(difficulty(n, 0.5, 0.8, 0.1))
+	l.BadBitChance = float32(difficulty(n, 0.1, 0.25, 0.05))
+	l.ViewDist = 11.5
+
+	// more of everything to find, and more security to get in the way
+	l.InitialData = 5 + depth/3
+	l.DataSpawnRate = float32(difficulty(n, 0.02, 0.04, 0.1))
+	l.InitialPowerUps = 3
+	l.PowerUpSpawnRate = 0.005
+	l.InitialItems = 2
+	l.ItemSpawnRate = 0.0025
+	l.EnemyCount = 4 + int(n)/2
+	l.EnemySpeed = float32(difficulty(n, 0.15, 0.3, 0.1))
+	l.EnemyViewDist = 10
+	l.TrackerCount = 2 + int(n)/3
+	l.TrackerSpeed = float32(difficulty(n, 0.12, 0.25, 0.1))
+	l.HunterCount = 3 + int(n)/4
+	l.HunterSpeed = float32(difficulty(n, 0.2, 0.35, 0.1))
+
+	// the amount of data needed keeps growing, and so does what it's worth so it doesn't take forever
+	scale := float32(1 + 0.25*n)
+	l.WinConditions = []WinCondition{
+		CollectData{Kind: DataKindDelta, Amount: 1000 * scale},
+		CollectData{Kind: DataKindLambda, Amount: 1000 * scale},
+		CollectData{Kind: DataKindSigma, Amount: 500 * scale},
+		CollectData{Kind: DataKindOmega, Amount: 100 * scale},
+	}
+	l.DataLootTable = lootTable{
+		{Data: DataKindDelta, Chance: 0.40},
+		{Data: DataKindLambda, Chance: 0.25},
+		{Data: DataKindSigma, Chance: 0.20},
+		{Data: DataKindOmega, Chance: 0.15},
+	}
+	worth := float32(1 + 0.1*n)
+	l.DataMultipliers = map[DataKind]float32{
+		DataKindDelta:  3 * worth,
+		DataKindLambda: 3 * worth,
+		DataKindSigma:  1.8 * worth,
+		DataKindOmega:  1.2 * worth,
+	}
+
+	l.Walls = generateWalls(rng, l.Width, l.Height, 4+depth/2)
+	l.Updater = generateUpdater(rng, depth)
+	l.ExitPlacement = []ExitPlacement{nil, ExitOnEdge{}, ExitFarthest{}, ExitNearGuard{}}[rng.Intn(4)]
+	l.BitGenerator = generateBits(rng, depth)
+	return l
+}
+
+// generateBits starts mixing up how bad bits come through, once the player has got used to them being scattered about.
+func generateBits(rng *rand.Rand, depth int) BitGenerator {
+	if depth < 8 {
+		return nil
+	}
+	// packets get bigger and the gaps in walls get smaller, up to a point
+	size, gap := 2+depth/8, 6-depth/10
+	if size > 5 {
+		size = 5
+	}
+	if gap < 2 {
+		gap = 2
+	}
+	switch rng.Intn(4) {
+	case 1:
+		return newNoiseBits(rng.Int63(), 4+rng.Float64()*4, 0.05)
+	case 2:
+		return newPacketBits(0.05, size)
+	case 3:
+		return newWallBits(0.02, gap)
+	}
+	return nil
+}
+
+// difficulty rises from start towards limit as n grows, getting a bit closer each level without ever reaching it.
+func difficulty(n, start, limit, rate float64) float64 {
+	return limit - (limit-start)*math.Pow(1-rate, n)
+}
+
+// generateWalls scatters some thin walls around the map, leaving a gap around the edges so nothing gets boxed in.
+func generateWalls(rng *rand.Rand, width, height, count int) []Wall {
+	walls := make([]Wall, 0, count)
+	for i := 0; i < count; i++ {
+		if rng.Intn(2) == 0 {
+			length := 4 + rng.Intn(10)
+			walls = append(walls, Wall{X: 3 + rng.Intn(width-length-6), Y: 3 + rng.Intn(height-6), Width: length, Height: 1})
+		} else {
+			length := 3 + rng.Intn(height/2)
+			walls = append(walls, Wall{X: 3 + rng.Intn(width-8), Y: 3 + rng.Intn(height-length-5), Width: 2, Height: length})
+		}
+	}
+	return walls
+}
+
+// generateUpdater picks a bit stream that gets more complicated with depth - more steps, shorter delays,
+// and eventually a random order instead of a loop.
+func generateUpdater(rng *rand.Rand, depth int) BitStreamUpdater {
+	count := depth - 3
+	if count < 1 {
+		count = 1
+	} else if count > 12 {
+		count = 12
+	}
+	longest := 20 - depth/2
+	if longest < 6 {
+		longest = 6
+	}
+	steps := make([]bitStreamStep, 0, count)
+	for i := 0; i < count; i++ {
+		steps = append(steps, bitStreamStep{
+			dir:   Direction(rng.Intn(int(MoveDownRight) + 1)),
+			delay: time.Duration(3+rng.Intn(longest-2)) * time.Second,
+		})
+	}
+	if depth >= 10 {
+		return newRandomBitStream(steps...)
+	}
+	return newLoopingBitStream(steps...)
+}
@@ -0,0 +1,280 @@
+package zen_doctor
+
+import "math/rand"
+
+// Enemy is anything in the world that is out to get the player.
+type Enemy interface {
+	Tick(s *GameState)
+	Location() Coordinate
+	// Render draws the enemy, and anything it can see, into the cell at c.
+	Render(c Coordinate, cell *Cell, mode CompatibilityMode)
+}
+
+func (w *World) spawnEnemies() {
+	for i := 0; i < w.Level.EnemyCount; i++ {
+		var route []Coordinate
+		if i < len(w.Level.PatrolRoutes) {
+			route = append(route, w.Level.PatrolRoutes[i]...)
+		} else if r, ok := randomPatrolRoute(w.rng, w.Level); ok {
+			route = r
+		} else {
+			// the level is too small to patrol
+			continue
+		}
+		// make sure the patroller can actually get to every part of its route
+		for j := range route {
+			route[j] = w.nearestOpenCell(route[j])
+		}
+		w.Enemies = append(w.Enemies, newPatroller(route[0], route, w.Level))
+	}
+	for i := 0; i < w.Level.TrackerCount; i++ {
+		w.Enemies = append(w.Enemies, newTracker(w.rng, w.randomOpenCell(), w.Level))
+	}
+}
+
+// minPatrolSize is the smallest level a random patrol route fits in.
+const minPatrolSize = 3
+
+// randomPatrolRoute walks around a random rectangle inside the level, shrinking it to fit small levels. It returns false
+// if the level is too small for any route.
+func randomPatrolRoute(rng *rand.Rand, level *LevelConfig) ([]Coordinate, bool) {
+	if level.Width < minPatrolSize || level.Height < minPatrolSize {
+		return nil, false
+	}
+	w := patrolSize(rng, 5, level.Width)
+	h := patrolSize(rng, 2, level.Height)
+	x1 := rng.Intn(level.Width - w - 1)
+	y1 := rng.Intn(level.Height - h - 1)
+	x2, y2 := x1+w, y1+h
+	return []Coordinate{{x1, y1}, {x2, y1}, {x2, y2}, {x1, y2}}, true
+}
+
+// patrolSize picks how far a patrol route goes along a side of the level that's length long. It leaves room for the
+// route to start at least one cell in from the far edge.
+func patrolSize(rng *rand.Rand, least, length int) int {
+	size := least
+	if length/3 > 0 {
+		size += rng.Intn(length / 3)
+	}
+	if size > length-2 {
+		size = length - 2
+	}
+	return size
+}
+
+// movement keeps track of partial steps, so enemies can move slower than once per tick.
+type movement struct {
+	progress float32
+	speed    float32
+}
+
+// tick returns how many whole steps to take this tick.
+func (m *movement) tick() int {
+	m.progress += m.speed
+	steps := int(m.progress)
+	m.progress -= float32(steps)
+	return steps
+}
+
+// patroller walks along a route, and raises the player's threat whenever they are within its vision cone.
+type patroller struct {
+	location Coordinate
+	facing   Direction
+	route    []Coordinate
+	next     int
+	movement movement
+	viewDist float64
+	viewCone float64
+	visible  map[Coordinate]bool
+}
+
+func newPatroller(start Coordinate, route []Coordinate, level *LevelConfig) *patroller {
+	return &patroller{
+		location: start,
+		facing:   MoveRight,
+		route:    route,
+		movement: movement{speed: level.EnemySpeed},
+		viewDist: level.EnemyViewDist,
+		viewCone: level.EnemyViewAngle,
+	}
+}
+
+func (p *patroller) Location() Coordinate {
+	return p.location
+}
+
+func (p *patroller) Tick(s *GameState) {
+	for steps := p.movement.tick(); steps > 0; steps-- {
+		next, dir, ok := s.world.stepToward(p.location, p.route[p.next])
+		if !ok {
+			// made it to the waypoint, or it can't be reached - either way, on to the next one
+			p.next = (p.next + 1) % len(p.route)
+			continue
+		}
+		p.location, p.facing = next, dir
+	}
+	p.look(&s.world)
+	if p.Sees(s.player.Location) {
+		s.player.tickThreat(s.level.EnemyThreat)
+	}
+}
+
+// look works out what is inside the vision cone, since walls can block the view.
+func (p *patroller) look(w *World) {
+	p.visible = make(map[Coordinate]bool)
+	r := int(p.viewDist)
+	for x := p.location.X - r; x <= p.location.X+r; x++ {
+		for y := p.location.Y - r/2; y <= p.location.Y+r/2; y++ {
+			c := Coordinate{x, y}
+			if p.location.InCone(p.viewDist, p.facing, p.viewCone, c) && w.lineOfSight(p.location, c) {
+				p.visible[c] = true
+			}
+		}
+	}
+}
+
+func (p *patroller) Sees(c Coordinate) bool {
+	return p.visible[c]
+}
+
+func (p *patroller) Render(c Coordinate, cell *Cell, mode CompatibilityMode) {
+	if p.location.Equals(c) {
+		cell.Foreground, cell.Symbol = Red, PatrollerSymbol.ForMode(mode)
+	} else if p.Sees(c) {
+		cell.Background = DarkRed
+	}
+}
+
+// tracker follows the freshest footprints it can smell, and raises the player's threat once it catches them.
+type tracker struct {
+	location   Coordinate
+	movement   movement
+	sniffRange float64
+	wandering  Direction
+	onTrail    bool
+}
+
+func newTracker(rng *rand.Rand, location Coordinate, level *LevelConfig) *tracker {
+	return &tracker{
+		location:   location,
+		movement:   movement{speed: level.TrackerSpeed},
+		sniffRange: level.TrackerSniffRange,
+		wandering:  Direction(rng.Intn(int(MoveDownRight) + 1)),
+	}
+}
+
+func (t *tracker) Location() Coordinate {
+	return t.location
+}
+
+func (t *tracker) Tick(s *GameState) {
+	for steps := t.movement.tick(); steps > 0; steps-- {
+		target, ok := t.sniff(s.world.Footprints)
+		t.onTrail = ok
+		if !ok {
+			// lost the trail, so just wander around until it finds another one
+			if s.rng.Float32() < 0.1 {
+				t.wandering = Direction(s.rng.Intn(int(MoveDownRight) + 1))
+			}
+			if target, ok = s.world.neighbor(t.location, t.wandering); !ok {
+				t.wandering = Direction(s.rng.Intn(int(MoveDownRight) + 1))
+				continue
+			}
+		}
+		if next, _, ok := s.world.stepToward(t.location, target); ok {
+			t.location = next
+		}
+	}
+	if t.location.Equals(s.player.Location) {
+		s.player.tickThreat(s.level.TrackerThreat)
+	}
+}
+
+// sniff finds the freshest footprint in range that is fresher than the one the tracker is standing on.
+func (t *tracker) sniff(footprints map[Coordinate]Footprint) (Coordinate, bool) {
+	best, found := t.location, false
+	freshest := footprints[t.location].Intensity
+	r := int(t.sniffRange)
+	for x := t.location.X - r; x <= t.location.X+r; x++ {
+		for y := t.location.Y - r/2; y <= t.location.Y+r/2; y++ {
+			c := Coordinate{x, y}
+			if footprint, ok := footprints[c]; ok && footprint.Intensity > freshest && t.location.InRange(t.sniffRange, c) {
+				best, freshest, found = c, footprint.Intensity, true
+			}
+		}
+	}
+	return best, found
+}
+
+func (t *tracker) Render(c Coordinate, cell *Cell, mode CompatibilityMode) {
+	if t.location.Equals(c) {
+		color := Yellow
+		if t.onTrail {
+			color = Red
+		}
+		cell.Foreground, cell.Symbol = color, TrackerSymbol.ForMode(mode)
+	}
+}
+
+// spawnHunters unleashes hunters from the edges of the map, as far from the player as it can manage.
+func (w *World) spawnHunters(player Coordinate) {
+	for i := 0; i < w.Level.HunterCount; i++ {
+		var c Coordinate
+		for attempt := 0; attempt < 10; attempt++ {
+			c = w.nearestOpenCell(randomEdge(w.rng, w.Level))
+			if !player.InRange(w.Level.ViewDist*2, c) {
+				break
+			}
+		}
+		w.Enemies = append(w.Enemies, newHunter(c, w.Level))
+	}
+}
+
+func randomEdge(rng *rand.Rand, level *LevelConfig) Coordinate {
+	x, y := rng.Intn(level.Width-1), rng.Intn(level.Height-1)
+	switch rng.Intn(4) {
+	case 0:
+		x = 0
+	case 1:
+		x = level.Width - 2
+	case 2:
+		y = 0
+	default:
+		y = level.Height - 2
+	}
+	return Coordinate{x, y}
+}
+
+// hunter always knows where the player is, and heads straight for them. Once it catches them, it's game over.
+type hunter struct {
+	location Coordinate
+	movement movement
+}
+
+func newHunter(location Coordinate, level *LevelConfig) *hunter {
+	return &hunter{
+		location: location,
+		movement: movement{speed: level.HunterSpeed},
+	}
+}
+
+func (h *hunter) Location() Coordinate {
+	return h.location
+}
+
+func (h *hunter) Tick(s *GameState) {
+	for steps := h.movement.tick(); steps > 0; steps-- {
+		if next, _, ok := s.world.stepToward(h.location, s.player.Location); ok {
+			h.location = next
+		}
+	}
+	if h.location.Equals(s.player.Location) {
+		s.caught = true
+	}
+}
+
+func (h *hunter) Render(c Coordinate, cell *Cell, mode CompatibilityMode) {
+	if h.location.Equals(c) {
+		cell.Foreground, cell.Symbol = Red, HunterSymbol.ForMode(mode)
+	}
+}
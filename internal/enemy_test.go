@@ -0,0 +1,120 @@
+package zen_doctor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRandomPatrolRoutes(t *testing.T) {
+	tests := map[string]struct {
+		width, height int
+		fits          bool
+	}{
+		"campaign":   {100, 20, true},
+		"narrow":     {7, 12, true},
+		"short":      {20, 3, true},
+		"smallest":   {3, 3, true},
+		"too narrow": {2, 10, false},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			level := GetLevel(Tutorial)
+			level.Width, level.Height = tc.width, tc.height
+			rng := testRand()
+			for i := 0; i < 50; i++ {
+				route, ok := randomPatrolRoute(rng, &level)
+				require.Equal(t, tc.fits, ok)
+				for _, c := range route {
+					assert.True(t, c.X >= 0 && c.X < tc.width-1 && c.Y >= 0 && c.Y < tc.height-1, "%v is off the level", c)
+				}
+			}
+		})
+	}
+}
+
+func TestInCone(t *testing.T) {
+	enemy := Coordinate{10, 10}
+	tests := map[string]struct {
+		facing Direction
+		other  Coordinate
+		want   bool
+	}{
+		"ahead":             {MoveRight, Coordinate{15, 10}, true},
+		"inside the edge":   {MoveRight, Coordinate{14, 11}, true},
+		"outside the edge":  {MoveRight, Coordinate{12, 12}, false},
+		"behind":            {MoveRight, Coordinate{6, 10}, false},
+		"out of range":      {MoveRight, Coordinate{18, 10}, false},
+		"below":             {MoveDown, Coordinate{10, 13}, true},
+		"too far below":     {MoveDown, Coordinate{10, 14}, false}, // rows count twice, since cells are tall
+		"diagonal":          {MoveUpLeft, Coordinate{8, 9}, true},
+		"its own cell":      {MoveRight, enemy, true},
+		"beside, diagonal":  {MoveUpLeft, Coordinate{11, 9}, false},
+		"ahead, other side": {MoveLeft, Coordinate{15, 10}, false},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, enemy.InCone(8, tc.facing, 60, tc.other))
+		})
+	}
+}
+
+func TestPatrollerSees(t *testing.T) {
+	level := layoutLevel(t, `
+............
+............
+......#.....
+`)
+	level.EnemyViewDist, level.EnemyViewAngle, level.EnemyThreat = 8, 60, 1
+	tests := map[string]struct {
+		player Coordinate
+		sees   bool
+	}{
+		"in the cone":     {Coordinate{5, 1}, true},
+		"far off":         {Coordinate{8, 0}, true},
+		"out of the cone": {Coordinate{3, 0}, false},
+		"behind":          {Coordinate{0, 1}, false},
+		"behind a wall":   {Coordinate{8, 2}, false},
+		"out of range":    {Coordinate{9, 1}, false},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := NewGameStateWithPlayerAt(tc.player, level, CompatibilityAscii, testRand(), SystemClock)
+			require.Equal(t, tc.player, s.player.Location)
+			p := newPatroller(Coordinate{1, 1}, []Coordinate{{1, 1}}, &level)
+			p.Tick(&s)
+			assert.Equal(t, tc.sees, p.Sees(tc.player))
+			if tc.sees {
+				assert.Equal(t, float32(1), s.player.Threat, "being seen raises the threat")
+			} else {
+				assert.Zero(t, s.player.Threat)
+			}
+		})
+	}
+}
+
+func TestPatrollerWalksItsRoute(t *testing.T) {
+	level := layoutLevel(t, `
+..........
+...#......
+..........
+..........
+`)
+	level.EnemySpeed = 1
+	s := NewGameStateWithPlayerAt(Coordinate{8, 3}, level, CompatibilityAscii, testRand(), SystemClock)
+	route := []Coordinate{{1, 1}, {6, 1}}
+	p := newPatroller(route[0], route, &level)
+
+	var path []Coordinate
+	for i := 0; i < 12; i++ {
+		p.Tick(&s)
+		path = append(path, p.Location())
+	}
+	// it pauses for a tick at each end of the route, and goes around the wall both ways
+	assert.Equal(t, []Coordinate{
+		{1, 1}, {2, 0}, {3, 0}, {4, 0}, {5, 1}, {6, 1},
+		{6, 1}, {5, 0}, {4, 0}, {3, 0}, {2, 0}, {1, 1},
+	}, path)
+	assert.Equal(t, MoveDownLeft, p.facing, "facing the way it went")
+}
@@ -0,0 +1,93 @@
+package zen_doctor
+
+import "time"
+
+// how often the parts of the game that don't depend on the level tick over
+const (
+	fixedRate     = time.Second / 30 // the world and the player
+	animationRate = time.Second / 11
+	automoveRate  = time.Second / 7
+)
+
+// Engine runs a game at its proper pace, without needing a terminal. Each part of the game ticks over on a cadence of
+// its own, all driven from one simulated clock, so a game can be played out in real time by stepping the engine as
+// time goes by - or as fast as the computer can go, by stepping it in big chunks.
+type Engine struct {
+	state    *GameState
+	clock    *ManualClock
+	elapsed  time.Duration // how much simulated time has gone by
+	cadences []*cadence
+}
+
+// cadence is one part of the game that ticks over at a steady rate.
+type cadence struct {
+	name     string
+	interval time.Duration
+	next     time.Duration // when it's next due, in simulated time
+	tick     func()
+}
+
+// NewEngine runs the game in state, which has to have been made with clock - the engine moves the clock along.
+func NewEngine(state *GameState, clock *ManualClock) *Engine {
+	e := &Engine{state: state, clock: clock}
+	// when more than one is due at the same moment, they tick in this order
+	e.every("world", fixedRate, func() {
+		state.TickWorld()
+		state.TickPlayer()
+	})
+	e.every("bit stream", time.Duration(float32(time.Second)/state.level.FPS), state.TickBitStream)
+	e.every("animations", animationRate, state.TickAnimations)
+	e.every("movement", automoveRate, state.TickMovement)
+	return e
+}
+
+func (e *Engine) every(name string, interval time.Duration, tick func()) {
+	e.cadences = append(e.cadences, &cadence{name: name, interval: interval, next: interval, tick: tick})
+}
+
+// Step moves the game dt further along, ticking over everything that comes due on the way. It stops early once the
+// level is over.
+func (e *Engine) Step(dt time.Duration) {
+	end := e.elapsed + dt
+	for !e.Done() {
+		due := e.nextDue()
+		if due.next > end {
+			break
+		}
+		e.advanceTo(due.next)
+		due.tick()
+		due.next += due.interval
+	}
+	if !e.Done() {
+		e.advanceTo(end)
+	}
+}
+
+func (e *Engine) nextDue() *cadence {
+	due := e.cadences[0]
+	for _, c := range e.cadences[1:] {
+		if c.next < due.next {
+			due = c
+		}
+	}
+	return due
+}
+
+func (e *Engine) advanceTo(t time.Duration) {
+	e.clock.Advance(t - e.elapsed)
+	e.elapsed = t
+}
+
+// Done returns true once the level is over, one way or the other.
+func (e *Engine) Done() bool {
+	return e.state.IsComplete() || e.state.IsGameOver()
+}
+
+// Elapsed returns how much simulated time has gone by since the engine started.
+func (e *Engine) Elapsed() time.Duration {
+	return e.elapsed
+}
+
+func (e *Engine) State() *GameState {
+	return e.state
+}
@@ -0,0 +1,73 @@
+package zen_doctor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEngine(level LevelConfig, c Coordinate) *Engine {
+	clock := NewManualClock(time.Unix(0, 0))
+	state := NewGameStateWithPlayerAt(c, level, CompatibilityAscii, testRand(), clock)
+	return NewEngine(&state, clock)
+}
+
+func TestEngineCadences(t *testing.T) {
+	level := GetLevel(Tutorial)
+	level.FPS = 2
+	tests := map[string]time.Duration{
+		"one big step":   time.Second,
+		"lots of frames": 10 * time.Millisecond,
+		"uneven frames":  7 * time.Millisecond,
+	}
+	for name, step := range tests {
+		t.Run(name, func(t *testing.T) {
+			e := newTestEngine(level, Coordinate{10, 10})
+			ticks := make(map[string]int)
+			for _, c := range e.cadences {
+				name := c.name
+				c.tick = func() { ticks[name]++ }
+			}
+			for e.Elapsed()+step <= time.Second {
+				e.Step(step)
+			}
+			e.Step(time.Second - e.Elapsed())
+
+			assert.Equal(t, map[string]int{"world": 30, "bit stream": 2, "animations": 11, "movement": 7}, ticks)
+			assert.Equal(t, time.Unix(1, 0), e.clock.Now(), "the game's clock keeps up with the engine")
+		})
+	}
+}
+
+func TestEnginePlaysALevel(t *testing.T) {
+	level := GetLevel(Tutorial)
+	level.Walls = nil
+	level.BitStreamChance = 0
+	level.WinConditions = []WinCondition{SurviveFor{Duration: 2 * time.Minute}}
+	level.ExitPlacement = ExitAt{X: 11, Y: 10}
+	e := newTestEngine(level, Coordinate{10, 10})
+	s := e.State()
+
+	started := time.Now()
+	e.Step(2*time.Minute + time.Second)
+	require.NotNil(t, s.world.Exit, "the exit opens once the player has survived")
+	s.MovePlayer(MoveRight)
+	e.Step(time.Minute)
+
+	assert.True(t, s.IsComplete())
+	assert.True(t, e.Done())
+	assert.Less(t, e.Elapsed(), 3*time.Minute, "the engine stops once the level is over")
+	assert.Less(t, time.Since(started), 5*time.Second, "minutes of game go by in no time")
+}
+
+func TestEngineStopsWhenTheLevelIsLost(t *testing.T) {
+	level := GetLevel(Tutorial)
+	level.LoseConditions = []LoseCondition{TimeLimit{Duration: time.Minute}}
+	e := newTestEngine(level, Coordinate{10, 10})
+
+	e.Step(time.Hour)
+	assert.True(t, e.State().IsFailed())
+	assert.Equal(t, time.Minute, e.Elapsed().Round(time.Second))
+}
@@ -0,0 +1,158 @@
+package zen_doctor
+
+// ExitPlacement picks where the exit opens up, once the level's win conditions are met.
+type ExitPlacement interface {
+	// Candidates lists the cells the exit could open up on, best first. The exit goes on the first one that isn't
+	// blocked by a wall, loot or the player - or the closest free cell to the first one, if they're all blocked.
+	Candidates(w *World, player Coordinate) []Coordinate
+}
+
+// ExitAt always puts the exit in the same place.
+type ExitAt struct {
+	X, Y int
+}
+
+func (e ExitAt) Candidates(_ *World, _ Coordinate) []Coordinate {
+	return []Coordinate{{e.X, e.Y}}
+}
+
+// ExitFarthest puts the exit as far away from the player as they can walk.
+type ExitFarthest struct{}
+
+func (ExitFarthest) Candidates(w *World, player Coordinate) []Coordinate {
+	reachable := w.walkableFrom(player)
+	for i, j := 0, len(reachable)-1; i < j; i, j = i+1, j-1 {
+		reachable[i], reachable[j] = reachable[j], reachable[i]
+	}
+	return reachable
+}
+
+// ExitOnEdge puts the exit somewhere random around the edge of the map - the part of it the player can get to.
+type ExitOnEdge struct{}
+
+func (ExitOnEdge) Candidates(w *World, _ Coordinate) []Coordinate {
+	right, bottom := w.Level.Width-2, w.Level.Height-2
+	var edge []Coordinate
+	for x := 0; x <= right; x++ {
+		edge = append(edge, Coordinate{x, 0}, Coordinate{x, bottom})
+	}
+	for y := 1; y < bottom; y++ {
+		edge = append(edge, Coordinate{0, y}, Coordinate{right, y})
+	}
+	w.rng.Shuffle(len(edge), func(i, j int) {
+		edge[i], edge[j] = edge[j], edge[i]
+	})
+	return edge
+}
+
+// ExitNearGuard puts the exit right next to one of the level's enemies, so getting out is a risk.
+type ExitNearGuard struct{}
+
+func (ExitNearGuard) Candidates(w *World, player Coordinate) []Coordinate {
+	if len(w.Enemies) == 0 {
+		return exitAnywhere{}.Candidates(w, player)
+	}
+	guard := w.Enemies[w.rng.Intn(len(w.Enemies))].Location()
+	var around []Coordinate
+	for d := MoveUp; d <= MoveDownRight; d++ {
+		dx, dy := d.Delta()
+		around = append(around, Coordinate{guard.X + dx, guard.Y + dy})
+	}
+	w.rng.Shuffle(len(around), func(i, j int) {
+		around[i], around[j] = around[j], around[i]
+	})
+	return around
+}
+
+// exitAnywhere is used when the level doesn't say, and picks one of the exits drawn on the layout, or else any cell.
+type exitAnywhere struct{}
+
+func (exitAnywhere) Candidates(w *World, player Coordinate) []Coordinate {
+	var exits []Coordinate
+	for _, c := range w.Level.Layout.exits() {
+		if w.inReach(c) {
+			exits = append(exits, c)
+		}
+	}
+	if len(exits) > 0 {
+		w.rng.Shuffle(len(exits), func(i, j int) {
+			exits[i], exits[j] = exits[j], exits[i]
+		})
+		return exits
+	}
+	// anywhere the player can walk to, which keeps it out of any walled off corners
+	walkable := w.walkableFrom(player)
+	if len(walkable) > 1 {
+		walkable = walkable[1:] // not where the player already is
+	}
+	return []Coordinate{walkable[w.rng.Intn(len(walkable))]}
+}
+
+// placeExit picks a cell for the exit using the level's placement.
+func (w *World) placeExit(player Coordinate) Coordinate {
+	placement := w.Level.ExitPlacement
+	if placement == nil {
+		placement = exitAnywhere{}
+	}
+	candidates := placement.Candidates(w, player)
+	if len(candidates) == 0 {
+		candidates = []Coordinate{w.randomOpenCell()}
+	}
+	isFree := func(c Coordinate) bool {
+		return w.isOpen(c) && w.inReach(c) && !w.DidCollideWithLoot(c) && !c.Equals(player)
+	}
+	for _, c := range candidates {
+		if isFree(c) {
+			return c
+		}
+	}
+	return w.nearestCellWhere(candidates[0], isFree)
+}
+
+// walkableFrom lists every cell the player could walk to from c, closest first.
+func (w *World) walkableFrom(c Coordinate) []Coordinate {
+	visited := map[Coordinate]bool{c: true}
+	queue := []Coordinate{c}
+	var walkable []Coordinate
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		walkable = append(walkable, next)
+		for d := MoveUp; d <= MoveRight; d++ {
+			n, ok := w.neighbor(next, d)
+			if ok && w.inReach(n) && !visited[n] {
+				visited[n] = true
+				queue = append(queue, n)
+			}
+		}
+	}
+	return walkable
+}
+
+// inReach returns true if the player could ever stand on c - they stop one short of the right and bottom edges.
+func (w *World) inReach(c Coordinate) bool {
+	return c.X >= 0 && c.X <= w.Level.Width-2 && c.Y >= 0 && c.Y <= w.Level.Height-2
+}
+
+// nearestCellWhere returns the closest cell to c that matches, searching outwards from c.
+func (w *World) nearestCellWhere(c Coordinate, match func(Coordinate) bool) Coordinate {
+	visited := map[Coordinate]bool{c: true}
+	queue := []Coordinate{c}
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		if match(next) {
+			return next
+		}
+		for d := MoveUp; d <= MoveRight; d++ {
+			dx, dy := d.Delta()
+			n := Coordinate{next.X + dx, next.Y + dy}
+			if visited[n] || n.X < 0 || n.X >= w.Level.Width || n.Y < 0 || n.Y >= w.Level.Height {
+				continue
+			}
+			visited[n] = true
+			queue = append(queue, n)
+		}
+	}
+	return w.randomOpenCell()
+}
@@ -0,0 +1,101 @@
+package zen_doctor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExitPlacement(t *testing.T) {
+	layout, err := ParseLayout([]byte(`
+..........
+.@..#.....
+....#.....
+....#.....
+`[1:]))
+	require.NoError(t, err)
+	level := GetLevel(Tutorial)
+	level.Walls = nil
+	level.InitialData, level.InitialPowerUps, level.InitialItems = 0, 0, 0
+	layout.apply(&level)
+	player := Coordinate{1, 1}
+
+	tests := map[string]struct {
+		placement ExitPlacement
+		check     func(t *testing.T, exit Coordinate)
+	}{
+		"fixed": {ExitAt{X: 7, Y: 2}, func(t *testing.T, exit Coordinate) {
+			assert.Equal(t, Coordinate{7, 2}, exit)
+		}},
+		"fixed on a wall": {ExitAt{X: 4, Y: 2}, func(t *testing.T, exit Coordinate) {
+			assert.Equal(t, 1, abs(exit.X-4)+abs(exit.Y-2), "moves to the closest free cell")
+		}},
+		"fixed on the player": {ExitAt{X: 1, Y: 1}, func(t *testing.T, exit Coordinate) {
+			assert.NotEqual(t, player, exit)
+		}},
+		"farthest": {ExitFarthest{}, func(t *testing.T, exit Coordinate) {
+			assert.Equal(t, Coordinate{8, 2}, exit, "the player can't get to the last row or column")
+		}},
+		"fixed on the last row and column": {ExitAt{X: 9, Y: 3}, func(t *testing.T, exit Coordinate) {
+			assert.Equal(t, Coordinate{8, 2}, exit)
+		}},
+		"edge": {ExitOnEdge{}, func(t *testing.T, exit Coordinate) {
+			assert.True(t, exit.X == 0 || exit.X == 8 || exit.Y == 0 || exit.Y == 2)
+		}},
+		"near guard": {ExitNearGuard{}, func(t *testing.T, exit Coordinate) {
+			assert.LessOrEqual(t, abs(exit.X-8), 1)
+			assert.LessOrEqual(t, abs(exit.Y-2), 1)
+		}},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			level := level
+			level.ExitPlacement = tc.placement
+			s := NewGameState(level, CompatibilityAscii, testRand(), SystemClock)
+			s.world.Enemies = []Enemy{newTracker(testRand(), Coordinate{8, 2}, &level)}
+			s.world.Loot = map[Coordinate]Loot{{8, 3}: {Kind: LootData}, {7, 3}: {Kind: LootData}}
+			for i := 0; i < 20; i++ {
+				exit := s.world.placeExit(player)
+				assert.True(t, s.world.isOpen(exit), "exit is on a wall")
+				assert.False(t, s.world.DidCollideWithLoot(exit), "exit is on loot")
+				assert.NotEqual(t, player, exit, "exit is on the player")
+				assert.True(t, exit.X < 9 && exit.Y < 3, "the player can't get to the exit")
+				tc.check(t, exit)
+			}
+		})
+	}
+}
+
+func TestExitAnywhere(t *testing.T) {
+	tests := map[string]string{
+		"no exits drawn": `
+..........
+.@....###.
+......#.#.
+......###.
+..........
+`,
+		"exit drawn out of reach": `
+..........
+.@....###.
+......#.#.
+......###.
+.........E
+`,
+	}
+	for name, drawing := range tests {
+		t.Run(name, func(t *testing.T) {
+			level := layoutLevel(t, drawing)
+			s := NewGameStateWithPlayerAt(Coordinate{1, 1}, level, CompatibilityAscii, testRand(), SystemClock)
+			for i := 0; i < 50; i++ {
+				candidates := exitAnywhere{}.Candidates(&s.world, s.player.Location)
+				require.Len(t, candidates, 1)
+				c := candidates[0]
+				assert.True(t, s.world.isOpen(c) && s.world.inReach(c), "the player can't get to %v", c)
+				assert.NotEqual(t, Coordinate{7, 2}, c, "the exit is walled off")
+				assert.NotEqual(t, s.player.Location, c)
+			}
+		})
+	}
+}
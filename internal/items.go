@@ -0,0 +1,148 @@
+package zen_doctor
+
+// MaxItems is how many items the player can hold, one for each number key.
+const MaxItems = 9
+
+type ItemKind int
+
+const (
+	ItemNone ItemKind = iota
+	ItemPylon
+	ItemPortal
+)
+
+func (k ItemKind) String() string {
+	switch k {
+	case ItemPylon:
+		return "Pylon"
+	case ItemPortal:
+		return "Portal"
+	}
+	return ""
+}
+
+func (k ItemKind) ForMode(mode CompatibilityMode) string {
+	switch k {
+	case ItemPylon:
+		return PylonSymbol.ForMode(mode)
+	case ItemPortal:
+		return PortalSymbol.ForMode(mode)
+	default:
+		return ` `
+	}
+}
+
+// Item is something the player carries around until they use it.
+type Item struct {
+	Kind   ItemKind
+	Rarity Rarity
+}
+
+// WorldObject is something the player has placed into the world by using an item.
+type WorldObject interface {
+	// Tick runs every time the bit stream moves, and returns false once the object should be removed.
+	Tick(s *GameState) bool
+	// Enter is called whenever the player moves onto c.
+	Enter(s *GameState, c Coordinate)
+	// Render draws the object into the cell at c, if it covers that cell.
+	Render(c Coordinate, cell *Cell, mode CompatibilityMode)
+}
+
+// deploy places the item into the world at c, and returns true if the item was used up.
+func (w *World) deploy(item Item, c Coordinate) bool {
+	switch item.Kind {
+	case ItemPylon:
+		w.Objects = append(w.Objects, &pylon{
+			Location:  c,
+			Radius:    w.Level.PylonRadius + float64(item.Rarity),
+			Integrity: 1,
+		})
+		return true
+	case ItemPortal:
+		// the first use opens one end of the portal, and the next one links it to the other end.
+		for _, obj := range w.Objects {
+			if p, ok := obj.(*portal); ok && p.To == nil {
+				if p.From.Equals(c) {
+					return false
+				}
+				p.To = &Coordinate{c.X, c.Y}
+				return true
+			}
+		}
+		w.Objects = append(w.Objects, &portal{From: c})
+	}
+	return false
+}
+
+// pylon neutralizes any bad bits that come within range of it, until it wears out.
+type pylon struct {
+	Location  Coordinate
+	Radius    float64
+	Integrity float32
+}
+
+func (p *pylon) Tick(s *GameState) bool {
+	p.Integrity += s.level.ItemDecayRate
+	r := int(p.Radius)
+	for x := p.Location.X - r; x <= p.Location.X+r; x++ {
+		for y := p.Location.Y - r/2; y <= p.Location.Y+r/2; y++ {
+			c := Coordinate{x, y}
+			if !p.Location.InRange(p.Radius, c) {
+				continue
+			}
+			if _, ok := s.bits.DidCollideWithBit(s.level, c, RevealedBitHarmful); ok {
+				s.bits.NeutralizeBit(c)
+			}
+		}
+	}
+	return p.Integrity > 0
+}
+
+func (p *pylon) Enter(_ *GameState, _ Coordinate) {}
+
+func (p *pylon) Render(c Coordinate, cell *Cell, mode CompatibilityMode) {
+	if p.Location.Equals(c) {
+		cell.Foreground, cell.Symbol = Teal, PylonSymbol.ForMode(mode)
+	} else if p.Location.InRange(p.Radius, c) {
+		cell.Background = DarkTeal
+	}
+}
+
+// portal moves the player between its two ends. It does nothing until both ends are placed.
+type portal struct {
+	From Coordinate
+	To   *Coordinate
+}
+
+func (p *portal) Tick(_ *GameState) bool {
+	return true
+}
+
+func (p *portal) Enter(s *GameState, c Coordinate) {
+	if p.To == nil {
+		return
+	}
+	var other Coordinate
+	switch {
+	case c.Equals(p.From):
+		other = *p.To
+	case c.Equals(*p.To):
+		other = p.From
+	default:
+		return
+	}
+	// don't send the player somewhere they couldn't have walked to
+	if s.world.isOpen(other) && s.world.inReach(other) {
+		s.player.Location = other
+	}
+}
+
+func (p *portal) Render(c Coordinate, cell *Cell, mode CompatibilityMode) {
+	color := LightBlue
+	if p.To == nil {
+		color = LightGray
+	}
+	if c.Equals(p.From) || (p.To != nil && c.Equals(*p.To)) {
+		cell.Foreground, cell.Symbol = color, PortalSymbol.ForMode(mode)
+	}
+}
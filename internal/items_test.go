@@ -0,0 +1,58 @@
+package zen_doctor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPortal(t *testing.T) {
+	level := layoutLevel(t, `
+..........
+..........
+..........
+.....#....
+..........
+`)
+	s := NewGameStateWithPlayerAt(Coordinate{1, 1}, level, CompatibilityAscii, testRand(), SystemClock)
+	item := Item{Kind: ItemPortal}
+
+	// the first end goes down, but the item isn't used up until the other end does
+	assert.False(t, s.world.deploy(item, Coordinate{2, 1}))
+	require.Len(t, s.world.Objects, 1)
+	p := s.world.Objects[0].(*portal)
+	assert.Nil(t, p.To)
+	assert.False(t, s.world.deploy(item, Coordinate{2, 1}), "both ends can't go in the same place")
+	assert.Nil(t, p.To)
+
+	// it goes nowhere until it's linked up
+	s.MovePlayer(MoveRight)
+	assert.Equal(t, Coordinate{2, 1}, s.player.Location)
+	s.MovePlayer(MoveLeft)
+
+	assert.True(t, s.world.deploy(item, Coordinate{7, 1}))
+	require.Len(t, s.world.Objects, 1, "the second end links up the first")
+	assert.Equal(t, &Coordinate{7, 1}, p.To)
+
+	// walking onto either end comes out of the other one
+	s.MovePlayer(MoveRight)
+	assert.Equal(t, Coordinate{7, 1}, s.player.Location)
+	s.MovePlayer(MoveRight)
+	s.MovePlayer(MoveLeft)
+	assert.Equal(t, Coordinate{2, 1}, s.player.Location)
+
+	// the player isn't sent anywhere they couldn't walk to
+	p.To = &Coordinate{5, 3}
+	s.MovePlayer(MoveLeft)
+	s.MovePlayer(MoveRight)
+	assert.Equal(t, Coordinate{2, 1}, s.player.Location, "the other end is on a wall")
+	p.To = &Coordinate{9, 1}
+	s.MovePlayer(MoveLeft)
+	s.MovePlayer(MoveRight)
+	assert.Equal(t, Coordinate{2, 1}, s.player.Location, "the other end is past where the player can go")
+
+	// a second portal starts over
+	assert.False(t, s.world.deploy(item, Coordinate{1, 2}))
+	assert.Len(t, s.world.Objects, 2)
+}
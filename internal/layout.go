@@ -0,0 +1,146 @@
+package zen_doctor
+
+import (
+	"bufio"
+	"bytes"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Layouts are drawn as text, one character per cell. The size of the drawing is the size of the level.
+const (
+	layoutOpen    = '.' // nothing special - spaces work too
+	layoutWall    = '#'
+	layoutData    = 'D' // data always turns up in one of these spots
+	layoutPowerUp = 'P' // power ups always turn up in one of these spots
+	layoutItem    = 'I' // items always turn up in one of these spots
+	layoutSpawn   = '@' // the player starts on one of these
+	layoutExit    = 'E' // the exit opens up on one of these
+	layoutEmitter = '*' // the bit stream flows out of these, instead of in from the edges of the map
+)
+
+// Layout is a hand-drawn map, for levels that need things to be in particular places.
+type Layout struct {
+	Width     int
+	Height    int
+	Walls     []Wall
+	LootSpots map[Coordinate]LootKind
+	Spawns    []Coordinate
+	Exits     []Coordinate
+	Emitters  []Coordinate
+}
+
+// ParseLayout reads a layout drawing. Lines shorter than the longest one are filled out with open cells.
+func ParseLayout(data []byte) (*Layout, error) {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimRight(scanner.Text(), "\r"))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	// blank lines at the end don't count
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	layout := &Layout{
+		Height:    len(lines),
+		LootSpots: make(map[Coordinate]LootKind),
+	}
+	for y, line := range lines {
+		runes := []rune(line)
+		if len(runes) > layout.Width {
+			layout.Width = len(runes)
+		}
+		for x, r := range runes {
+			c := Coordinate{x, y}
+			switch r {
+			case layoutOpen, ' ':
+			case layoutWall:
+				// join up walls along the row, so there aren't so many of them
+				if n := len(layout.Walls); n > 0 && layout.Walls[n-1].Y == y && layout.Walls[n-1].X+layout.Walls[n-1].Width == x {
+					layout.Walls[n-1].Width++
+				} else {
+					layout.Walls = append(layout.Walls, Wall{X: x, Y: y, Width: 1, Height: 1})
+				}
+			case layoutData:
+				layout.LootSpots[c] = LootData
+			case layoutPowerUp:
+				layout.LootSpots[c] = LootPowerUp
+			case layoutItem:
+				layout.LootSpots[c] = LootItem
+			case layoutSpawn:
+				layout.Spawns = append(layout.Spawns, c)
+			case layoutExit:
+				layout.Exits = append(layout.Exits, c)
+			case layoutEmitter:
+				layout.Emitters = append(layout.Emitters, c)
+			default:
+				return nil, errors.Errorf("line %d, column %d: unknown map character %q", y+1, x+1, r)
+			}
+		}
+	}
+	if layout.Width == 0 || layout.Height == 0 {
+		return nil, errors.New("map is empty")
+	}
+	return layout, nil
+}
+
+// apply sets the size of the level to match the layout, and adds its walls.
+func (layout *Layout) apply(l *LevelConfig) {
+	l.Layout = layout
+	l.Width = layout.Width
+	l.Height = layout.Height
+	l.Walls = append(l.Walls, layout.Walls...)
+}
+
+// lootSpots returns where loot of the given kind has to go, or nothing if it can go anywhere.
+func (layout *Layout) lootSpots(kind LootKind) []Coordinate {
+	if layout == nil {
+		return nil
+	}
+	var spots []Coordinate
+	for c, k := range layout.LootSpots {
+		if k == kind {
+			spots = append(spots, c)
+		}
+	}
+	// keep the order stable, so the same random numbers pick the same spots
+	sort.Slice(spots, func(i, j int) bool {
+		return spots[i].Y < spots[j].Y || (spots[i].Y == spots[j].Y && spots[i].X < spots[j].X)
+	})
+	return spots
+}
+
+// spawnNear returns the spawn point closest to c, if the layout has any.
+func (layout *Layout) spawnNear(c Coordinate) (Coordinate, bool) {
+	if layout == nil || len(layout.Spawns) == 0 {
+		return c, false
+	}
+	distance := func(other Coordinate) int {
+		return abs(other.X-c.X) + abs(other.Y-c.Y)
+	}
+	best := layout.Spawns[0]
+	for _, spawn := range layout.Spawns[1:] {
+		if distance(spawn) < distance(best) {
+			best = spawn
+		}
+	}
+	return best, true
+}
+
+// exits returns a copy of the exits drawn on the layout, if there are any.
+func (layout *Layout) exits() []Coordinate {
+	if layout == nil {
+		return nil
+	}
+	return append([]Coordinate(nil), layout.Exits...)
+}
+
+func (layout *Layout) hasEmitters() bool {
+	return layout != nil && len(layout.Emitters) > 0
+}
@@ -8,6 +8,9 @@ import (
 
 type LevelConfig struct {
 	Level              Level
+	Title              string               // shown instead of the level number, if set
+	Kind               LevelKind            // what sort of level this is
+	MazeSeed           int64                // seed for generating the maze in mainframe levels - random if 0
 	Width              int                  // Width of map
 	Height             int                  // Height of map
 	ViewDist           float64              // radius in which the player can view. automatically scaled 2x in the X direction.
@@ -19,7 +22,10 @@ type LevelConfig struct {
 	BitStreamChance    float32              // % chance a bit will appear
 	GoodBitChance      float32              // % chance a bit will be good
 	BadBitChance       float32              // % chance a bit will be good
+	BitGenerator       BitGenerator         // how new bits are made - each one is rolled on its own if nil
 	ThreatByRarity     map[Rarity]float32   // amount of threat generated by stream, based on their rarity
+	HitInvulnerability time.Duration        // how long bad bits pass through the player after one hits them
+	HitFlashRate       time.Duration        // how fast the player blinks while bad bits pass through them - 0 to not blink
 	LeaveSpeed         float32              // how fast the player can leave the room
 	LeaveSpeedDecay    float32              // how fast the player can leave the room
 	InitialData        int                  // how much data is spawned into the world when it's loaded
@@ -39,27 +45,74 @@ type LevelConfig struct {
 	DataMultipliers    map[DataKind]float32 // multiplier for how much data is worth, by loot type
 	WinConditions      []WinCondition       // what is required to unlock the exit to this room
 	Updater            BitStreamUpdater     // different levels have different bit streams
+	ExitPlacement      ExitPlacement        // where the exit opens up once it's unlocked - anywhere at random if nil
+
+	// power up effects, once collected
+	PowerUpDuration    map[PowerUpKind]time.Duration // how long each kind of power up lasts
+	PowerUpByRarity    map[Rarity]float32            // multiplier for how long power ups last, by loot rarity
+	PowerUpViewDist    float64                       // extra view distance while the vision range power up is active
+	PowerUpThreatDecay float32                       // multiplier for threat decay while the threat decay power up is active
+	PowerUpLootSpeed   float32                       // multiplier for loot speed while the loot speed power up is active
+
+	// usable items
+	InitialItems  int       // how many items are spawned into the world at the beginning
+	ItemSpawnRate float32   // how fast new items are spawned
+	ItemLootTable lootTable // % chance for items to be spawned - values in this table should add to 1
+	ItemDecayRate float32   // how fast deployed items wear out, each time the bit stream moves
+	PylonRadius   float64   // radius in which a deployed pylon neutralizes bad bits, increased by rarity
+
+	// security enemies
+	EnemyCount        int            // how many patrolling enemies are in the level
+	EnemySpeed        float32        // how many cells an enemy moves each world tick
+	EnemyViewDist     float64        // how far enemies can see. automatically scaled 2x in the X direction.
+	EnemyViewAngle    float64        // how wide an enemy's vision cone is, in degrees
+	EnemyThreat       float32        // how much threat is generated each world tick while an enemy can see the player
+	PatrolRoutes      [][]Coordinate // routes for patrolling enemies to walk - any enemies without one get a random route
+	TrackerCount      int            // how many enemies are following the player's footprints
+	TrackerSpeed      float32        // how many cells a tracker moves each world tick
+	TrackerSniffRange float64        // how far away a tracker can smell footprints. automatically scaled 2x in the X direction.
+	TrackerThreat     float32        // how much threat is generated each world tick while a tracker has caught the player
+	HunterCount       int            // how many hunters are unleashed once the player reaches max threat - if none, it's game over right away
+	HunterSpeed       float32        // how many cells a hunter moves each world tick
+
+	// obstacles
+	Walls []Wall // walls block the player, enemies and the bit stream, leaving a shadow behind them
+
+	// ways to lose
+	LoseConditions []LoseCondition // fail states on top of being caught, like running out of time
+
+	// hand-drawn maps
+	Layout *Layout // where loot, spawns, exits and bit stream emitters go - anywhere at random if nil
 }
 
 func (l LevelConfig) Name() string {
+	if l.Title != "" {
+		return l.Title
+	}
 	return l.Level.String()
 }
 
+// PowerUpDurationFor returns how long a collected power up lasts, scaled by its rarity.
+func (l LevelConfig) PowerUpDurationFor(kind PowerUpKind, rarity Rarity) time.Duration {
+	multiplier, ok := l.PowerUpByRarity[rarity]
+	if !ok {
+		multiplier = 1
+	}
+	return time.Duration(float32(l.PowerUpDuration[kind]) * multiplier)
+}
+
 func (l LevelConfig) IsValid() bool {
 	return l.Level.IsValid() && l.Width > 0 && l.Height > 0
 }
 
-type WinCondition struct {
-	Kind   DataKind
-	Amount float32
-}
+type LevelKind int
 
-func (w WinCondition) IsMet(state *GameState) bool {
-	if amount, ok := state.player.DataCollected[w.Kind]; ok {
-		return amount >= w.Amount
-	}
-	return false
-}
+const (
+	// LevelKindFirewall levels are open rooms, with a bit stream flowing through them
+	LevelKindFirewall LevelKind = iota
+	// LevelKindMainframe levels are mazes with no bit stream
+	LevelKindMainframe
+)
 
 type LootTable interface {
 	Chance(int) float32
@@ -67,9 +120,9 @@ type LootTable interface {
 }
 
 // Given a loot table, this will pick one and return its index.
-func pickOne(options LootTable) int {
+func pickOne(rng *rand.Rand, options LootTable) int {
 
-	picked := rand.Float32()
+	picked := rng.Float32()
 	lower := float32(0.0)
 	for i := 0; i < options.Len(); i++ {
 		upper := lower + options.Chance(i)
@@ -90,28 +143,50 @@ const (
 	Level3
 	Level4
 	Level5
+	Mainframe1
+	Mainframe2
+	Vault
 )
 
+// the main route through the built in levels
+var campaign = []Level{Tutorial, Level1, Level2, Level3, Mainframe1, Level4, Level5, Mainframe2}
+
 func (l Level) Equals(i int) bool {
 	return int(l) == i
 }
 
 func (l Level) Inc() Level {
-	return l + 1
+	for i, level := range campaign {
+		if level == l && i+1 < len(campaign) {
+			return campaign[i+1]
+		}
+	}
+	return -1
 }
 
 func (l Level) Dec() Level {
-	return l - 1
+	for i, level := range campaign {
+		if level == l && i > 0 {
+			return campaign[i-1]
+		}
+	}
+	return -1
 }
 
 func (l Level) IsValid() bool {
-	return l >= Tutorial && l <= Level5
+	return l >= Tutorial && l <= Vault
 }
 
 func (l Level) String() string {
 	switch l {
 	case Tutorial:
 		return "Level 0: Tutorial"
+	case Mainframe1:
+		return "Mainframe 1"
+	case Mainframe2:
+		return "Mainframe 2"
+	case Vault:
+		return "The Vault"
 	default:
 		return fmt.Sprintf("Level %d", int(l))
 	}
@@ -139,14 +214,16 @@ func defaultLevel() LevelConfig {
 			Common:    5,
 			Junk:      4,
 		},
-		LeaveSpeed:      2,
-		LeaveSpeedDecay: -0.1,
-		InitialData:     1,
-		DefaultData:     DataKindDelta,
-		LootSpeed:       1,
-		LootSpeedDecay:  -0.3,
-		DataSpawnRate:   0.003,
-		DataDecayRate:   -0.001,
+		HitInvulnerability: 750 * time.Millisecond,
+		HitFlashRate:       125 * time.Millisecond,
+		LeaveSpeed:         2,
+		LeaveSpeedDecay:    -0.1,
+		InitialData:        1,
+		DefaultData:        DataKindDelta,
+		LootSpeed:          1,
+		LootSpeedDecay:     -0.3,
+		DataSpawnRate:      0.003,
+		DataDecayRate:      -0.001,
 		DataLootTable: lootTable{
 			{Data: DataKindDelta, Chance: 1.00},
 		},
@@ -182,12 +259,48 @@ func defaultLevel() LevelConfig {
 			DataKindOmega:  1,
 		},
 		WinConditions: []WinCondition{
-			{
+			CollectData{
 				Kind:   DataKindDelta,
 				Amount: 100,
 			},
 		},
 		Updater: newLinearBitStream(MoveLeft),
+		PowerUpDuration: map[PowerUpKind]time.Duration{
+			PowerUpVisionRange:    20 * time.Second,
+			PowerUpThreatDecay:    15 * time.Second,
+			PowerUpBadBitImmunity: 10 * time.Second,
+			PowerUpBadBitsAreGood: 8 * time.Second,
+			PowerUpLootSpeed:      15 * time.Second,
+		},
+		PowerUpByRarity: map[Rarity]float32{
+			Legendary: 3,
+			Epic:      2,
+			Rare:      1.5,
+			Uncommon:  1.25,
+			Common:    1,
+			Junk:      0.5,
+		},
+		PowerUpViewDist:    5,
+		PowerUpThreatDecay: 3,
+		PowerUpLootSpeed:   2,
+		ItemSpawnRate:      0,
+		ItemLootTable: lootTable{
+			{Item: ItemPylon, Chance: 0.50},
+			{Item: ItemPortal, Chance: 0.50},
+		},
+		ItemDecayRate:     -0.01,
+		PylonRadius:       4.5,
+		EnemyCount:        0,
+		EnemySpeed:        0.1,
+		EnemyViewDist:     8,
+		EnemyViewAngle:    60,
+		EnemyThreat:       0.3,
+		TrackerCount:      0,
+		TrackerSpeed:      0.12,
+		TrackerSniffRange: 4,
+		TrackerThreat:     1,
+		HunterCount:       1,
+		HunterSpeed:       0.15,
 	}
 }
 
@@ -202,14 +315,18 @@ func GetLevel(level Level) LevelConfig {
 		l.BitStreamChance = 0.25
 		l.PowerUpSpawnRate = 0.001
 		l.WinConditions = []WinCondition{
-			{Kind: DataKindDelta, Amount: 200},
-			{Kind: DataKindLambda, Amount: 50},
+			CollectData{Kind: DataKindDelta, Amount: 200},
+			CollectData{Kind: DataKindLambda, Amount: 50},
 		}
 		l.DataLootTable = lootTable{
 			{Data: DataKindDelta, Chance: 0.66},
 			{Data: DataKindLambda, Chance: 0.34},
 		}
 		l.DataMultipliers[DataKindDelta] = 1.2
+		l.Walls = []Wall{
+			{X: 30, Y: 6, Width: 2, Height: 8},
+			{X: 68, Y: 6, Width: 2, Height: 8},
+		}
 		l.Updater = newLoopingBitStream(bitStreamStep{
 			dir:   MoveLeft,
 			delay: 10 * time.Second,
@@ -231,11 +348,13 @@ func GetLevel(level Level) LevelConfig {
 		l.DataSpawnRate = 0.004
 		l.InitialPowerUps = 1
 		l.PowerUpSpawnRate = 0.002
+		l.ItemSpawnRate = 0.001
+		l.EnemyCount = 1
 		l.ViewDist = 8.5
 		l.WinConditions = []WinCondition{
-			{Kind: DataKindDelta, Amount: 300},
-			{Kind: DataKindLambda, Amount: 100},
-			{Kind: DataKindSigma, Amount: 25},
+			CollectData{Kind: DataKindDelta, Amount: 300},
+			CollectData{Kind: DataKindLambda, Amount: 100},
+			CollectData{Kind: DataKindSigma, Amount: 25},
 		}
 		l.DataLootTable = lootTable{
 			{Data: DataKindDelta, Chance: 0.50},
@@ -247,6 +366,11 @@ func GetLevel(level Level) LevelConfig {
 			DataKindLambda: 1.2,
 			DataKindSigma:  1,
 		}
+		l.Walls = []Wall{
+			{X: 20, Y: 4, Width: 6, Height: 3},
+			{X: 50, Y: 12, Width: 8, Height: 3},
+			{X: 75, Y: 5, Width: 4, Height: 4},
+		}
 		l.Updater = newLoopingBitStream(rotatingBitStream(10*time.Second, 20*time.Second, 5*time.Second)...)
 
 	case Level3:
@@ -256,12 +380,17 @@ func GetLevel(level Level) LevelConfig {
 		l.DataSpawnRate = 0.008
 		l.InitialPowerUps = 1
 		l.PowerUpSpawnRate = 0.003
+		l.InitialItems = 1
+		l.ItemSpawnRate = 0.0015
+		l.EnemyCount = 2
+		l.TrackerCount = 1
+		l.HunterCount = 2
 		l.ViewDist = 9.5
 		l.WinConditions = []WinCondition{
-			{Kind: DataKindDelta, Amount: 500},
-			{Kind: DataKindLambda, Amount: 200},
-			{Kind: DataKindSigma, Amount: 100},
-			{Kind: DataKindOmega, Amount: 20},
+			CollectData{Kind: DataKindDelta, Amount: 500},
+			CollectData{Kind: DataKindLambda, Amount: 200},
+			CollectData{Kind: DataKindSigma, Amount: 100},
+			CollectData{Kind: DataKindOmega, Amount: 20},
 		}
 		l.DataLootTable = lootTable{
 			{Data: DataKindDelta, Chance: 0.40},
@@ -275,6 +404,11 @@ func GetLevel(level Level) LevelConfig {
 			DataKindSigma:  1.2,
 			DataKindOmega:  1,
 		}
+		l.Walls = []Wall{
+			{X: 15, Y: 5, Width: 12, Height: 1},
+			{X: 45, Y: 10, Width: 15, Height: 1},
+			{X: 75, Y: 4, Width: 10, Height: 1},
+		}
 		l.Updater = newLoopingBitStream(zigZagBitStream(20*time.Second, 5*time.Second)...)
 
 	case Level4:
@@ -284,12 +418,19 @@ func GetLevel(level Level) LevelConfig {
 		l.DataSpawnRate = 0.01
 		l.InitialPowerUps = 2
 		l.PowerUpSpawnRate = 0.004
+		l.InitialItems = 1
+		l.ItemSpawnRate = 0.002
+		l.EnemyCount = 3
+		l.TrackerCount = 1
+		l.EnemySpeed = 0.12
+		l.HunterCount = 2
+		l.HunterSpeed = 0.17
 		l.ViewDist = 10.5
 		l.WinConditions = []WinCondition{
-			{Kind: DataKindDelta, Amount: 750},
-			{Kind: DataKindLambda, Amount: 500},
-			{Kind: DataKindSigma, Amount: 250},
-			{Kind: DataKindOmega, Amount: 75},
+			CollectData{Kind: DataKindDelta, Amount: 750},
+			CollectData{Kind: DataKindLambda, Amount: 500},
+			CollectData{Kind: DataKindSigma, Amount: 250},
+			CollectData{Kind: DataKindOmega, Amount: 75},
 		}
 		l.DataLootTable = lootTable{
 			{Data: DataKindDelta, Chance: 0.40},
@@ -303,7 +444,14 @@ func GetLevel(level Level) LevelConfig {
 			DataKindSigma:  1.5,
 			DataKindOmega:  1.2,
 		}
+		l.Walls = []Wall{
+			{X: 10, Y: 8, Width: 3, Height: 4},
+			{X: 35, Y: 3, Width: 10, Height: 2},
+			{X: 60, Y: 14, Width: 10, Height: 2},
+			{X: 85, Y: 7, Width: 3, Height: 6},
+		}
 		l.Updater = newLoopingBitStream(rotatingBitStream(10*time.Second, 5*time.Second, 20*time.Second)...)
+		l.ExitPlacement = ExitOnEdge{}
 
 	case Level5:
 		l.FPS = 7
@@ -312,12 +460,20 @@ func GetLevel(level Level) LevelConfig {
 		l.DataSpawnRate = 0.02
 		l.InitialPowerUps = 3
 		l.PowerUpSpawnRate = 0.005
+		l.InitialItems = 2
+		l.ItemSpawnRate = 0.0025
+		l.EnemyCount = 4
+		l.TrackerCount = 2
+		l.EnemySpeed = 0.15
+		l.EnemyViewDist = 10
+		l.HunterCount = 3
+		l.HunterSpeed = 0.2
 		l.ViewDist = 11.5
 		l.WinConditions = []WinCondition{
-			{Kind: DataKindDelta, Amount: 1000},
-			{Kind: DataKindLambda, Amount: 1000},
-			{Kind: DataKindSigma, Amount: 500},
-			{Kind: DataKindOmega, Amount: 100},
+			CollectData{Kind: DataKindDelta, Amount: 1000},
+			CollectData{Kind: DataKindLambda, Amount: 1000},
+			CollectData{Kind: DataKindSigma, Amount: 500},
+			CollectData{Kind: DataKindOmega, Amount: 100},
 		}
 		l.DataLootTable = lootTable{
 			{Data: DataKindDelta, Chance: 0.40},
@@ -331,7 +487,128 @@ func GetLevel(level Level) LevelConfig {
 			DataKindSigma:  1.8,
 			DataKindOmega:  1.2,
 		}
+		l.Walls = []Wall{
+			{X: 20, Y: 5, Width: 2, Height: 10},
+			{X: 40, Y: 2, Width: 12, Height: 2},
+			{X: 40, Y: 16, Width: 12, Height: 2},
+			{X: 70, Y: 8, Width: 6, Height: 4},
+			{X: 88, Y: 3, Width: 2, Height: 14},
+		}
 		l.Updater = newRandomBitStream(rotatingBitStream(10*time.Second, 5*time.Second, 20*time.Second)...)
+
+	case Mainframe1:
+		mainframeLevel(&l)
+		l.InitialData = 6
+		l.EnemyCount = 2
+		l.TrackerCount = 1
+		l.HunterCount = 2
+		l.WinConditions = []WinCondition{
+			CollectData{Kind: DataKindSigma, Amount: 150},
+			CollectData{Kind: DataKindOmega, Amount: 50},
+		}
+		l.DataLootTable = lootTable{
+			{Data: DataKindSigma, Chance: 0.60},
+			{Data: DataKindOmega, Chance: 0.40},
+		}
+		l.DataMultipliers = map[DataKind]float32{
+			DataKindSigma: 1.5,
+			DataKindOmega: 1,
+		}
+
+	case Mainframe2:
+		mainframeLevel(&l)
+		l.InitialData = 8
+		l.ViewDist = 5.5
+		l.EnemyCount = 4
+		l.EnemySpeed = 0.12
+		l.TrackerCount = 2
+		l.HunterCount = 3
+		l.HunterSpeed = 0.18
+		l.WinConditions = []WinCondition{
+			CollectData{Kind: DataKindSigma, Amount: 300},
+			CollectData{Kind: DataKindOmega, Amount: 150},
+		}
+		l.DataLootTable = lootTable{
+			{Data: DataKindSigma, Chance: 0.50},
+			{Data: DataKindOmega, Chance: 0.50},
+		}
+		l.DataMultipliers = map[DataKind]float32{
+			DataKindSigma: 2,
+			DataKindOmega: 1.5,
+		}
+
+	case Vault:
+		// a hidden level, full of rare loot that doesn't stick around for long
+		l.FPS = 4
+		l.BitStreamChance = 0.3
+		l.BadBitChance = 0.2
+		l.InitialData = 8
+		l.DataSpawnRate = 0.015
+		l.DataDecayRate = -0.004
+		l.InitialPowerUps = 2
+		l.PowerUpSpawnRate = 0.003
+		l.TrackerCount = 2
+		l.HunterCount = 2
+		l.ViewDist = 9.5
+		l.LootChanceByRarity = map[Rarity]float32{
+			Legendary: 0.05,
+			Epic:      0.25,
+			Rare:      0.50,
+			Uncommon:  0.80,
+			Common:    1.00,
+		}
+		l.WinConditions = []WinCondition{
+			CollectData{Kind: DataKindOmega, Amount: 300},
+			ThreatUnder{Percent: 50}, // don't let them know you were ever here
+		}
+		l.LoseConditions = []LoseCondition{
+			TimeLimit{Duration: 4 * time.Minute}, // the vault won't stay open forever
+		}
+		l.ExitPlacement = ExitNearGuard{}
+		l.DataLootTable = lootTable{
+			{Data: DataKindSigma, Chance: 0.50},
+			{Data: DataKindOmega, Chance: 0.50},
+		}
+		l.DataMultipliers = map[DataKind]float32{
+			DataKindSigma: 1,
+			DataKindOmega: 1,
+		}
+		l.Walls = []Wall{
+			{X: 35, Y: 4, Width: 30, Height: 1},
+			{X: 35, Y: 15, Width: 30, Height: 1},
+			{X: 35, Y: 4, Width: 2, Height: 4},
+			{X: 35, Y: 12, Width: 2, Height: 4},
+			{X: 63, Y: 4, Width: 2, Height: 4},
+			{X: 63, Y: 12, Width: 2, Height: 4},
+		}
+		l.Updater = newLoopingBitStream(zigZagBitStream(10*time.Second, 5*time.Second)...)
 	}
 	return l
 }
+
+// mainframeLevel sets up the parts that all mainframe levels have in common: a maze with no bit stream.
+// Without good bits to clear threat, moving around is a little quieter, and data lasts longer so there's time to find it.
+func mainframeLevel(l *LevelConfig) {
+	l.Kind = LevelKindMainframe
+	l.Width = 99 // odd sizes fit the maze with a single wall all the way around
+	l.Height = 19
+	l.BitStreamChance = 0
+	l.ViewDist = 6.5
+	l.MovementThreat = 0.15
+	l.ExitPlacement = ExitFarthest{} // the way out is at the bottom of the maze
+	l.DataSpawnRate = 0.002
+	l.DataDecayRate = -0.0002
+	l.InitialPowerUps = 2
+	l.PowerUpSpawnRate = 0.002
+	l.PowerUpLootTable = lootTable{
+		{PowerUp: PowerUpLootSpeed, Chance: 0.40},
+		{PowerUp: PowerUpThreatDecay, Chance: 0.30},
+		{PowerUp: PowerUpVisionRange, Chance: 0.30},
+	}
+	l.InitialItems = 1
+	l.ItemSpawnRate = 0.001
+	l.ItemLootTable = lootTable{
+		{Item: ItemPortal, Chance: 1.00},
+	}
+	l.Updater = newStaticBitStream()
+}
@@ -0,0 +1,590 @@
+package zen_doctor
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Level files are JSON or YAML documents, keyed by the names of the fields in LevelConfig. Anything that isn't in the
+// file is taken from the default level, and maps are merged with the defaults. For example:
+//
+//   {
+//     "Title": "Crossfire",
+//     "FPS": 3,
+//     "BitStreamChance": 0.4,
+//     "ThreatByRarity": {"Legendary": 25},
+//     "DataLootTable": [{"Data": "Delta", "Chance": 0.5}, {"Data": "Lambda", "Chance": 0.5}],
+//     "WinConditions": [{"Kind": "Delta", "Amount": 150}, {"Type": "AnyOf", "Conditions": [
+//       {"Type": "Survive", "Duration": "2m"}, {"Type": "CollectRarity", "Rarity": "Epic", "Count": 2}
+//     ]}],
+//     "LoseConditions": [{"Type": "TimeLimit", "Duration": "5m"}, {"Type": "DataLost", "Kind": "Omega"}],
+//     "BitGenerator": {"Type": "noise", "Seed": 7, "Size": 6, "Drift": 0.05},
+//     "ExitPlacement": {"Type": "Fixed", "X": 60, "Y": 2},
+//     "PowerUpDuration": {"VisionRange": "30s"},
+//     "HitInvulnerability": "1s",
+//     "Updater": {"Type": "looping", "Steps": [{"Dir": "Left", "Delay": "10s"}, {"Dir": "DownLeft", "Delay": "5s"}]}
+//   }
+//
+// A Layout can be drawn right in the file, or kept in a text file of its own next to it - see ParseLayout.
+
+// levelFile overrides the fields of LevelConfig that can't be decoded directly.
+type levelFile struct {
+	*LevelConfig
+	PowerUpDuration    map[PowerUpKind]duration
+	HitInvulnerability *duration
+	HitFlashRate       *duration
+	Updater            *updaterSpec
+	BitGenerator       *generatorSpec
+	ExitPlacement      *exitSpec
+	WinConditions      []winConditionSpec
+	LoseConditions     []loseConditionSpec
+	Layout             string // a map drawing, or the path to one relative to the level file
+}
+
+// updaterSpec describes a BitStreamUpdater.
+type updaterSpec struct {
+	Type   string     // one of linear, looping, random, static, choreography or lanes
+	Dir    Direction  // which way a linear bit stream moves
+	Steps  []stepSpec // the steps for looping and random bit streams
+	Script string     // the script for choreography bit streams - see ParseChoreography
+	Lanes  []laneSpec // the rows or columns for lanes bit streams, repeated if there are fewer than the map has
+}
+
+type stepSpec struct {
+	Dir   Direction
+	Delay duration
+}
+
+type laneSpec struct {
+	Dir   Direction // Left or Right for rows, Up or Down for columns
+	Speed float32   // how many cells the lane moves each tick
+}
+
+func (u updaterSpec) build() (BitStreamUpdater, error) {
+	steps := make([]bitStreamStep, 0, len(u.Steps))
+	for _, step := range u.Steps {
+		steps = append(steps, bitStreamStep{dir: step.Dir, delay: time.Duration(step.Delay)})
+	}
+	switch strings.ToLower(u.Type) {
+	case "linear":
+		return newLinearBitStream(u.Dir), nil
+	case "static":
+		return newStaticBitStream(), nil
+	case "choreography":
+		updater, err := ParseChoreography(u.Script)
+		return updater, errors.Wrap(err, "Updater.Script")
+	case "lanes":
+		return u.buildLanes()
+	case "looping", "random":
+		if len(steps) == 0 {
+			return nil, errors.Errorf("Updater.Steps: %s bit stream needs at least one step", u.Type)
+		}
+		if strings.ToLower(u.Type) == "random" {
+			return newRandomBitStream(steps...), nil
+		}
+		return newLoopingBitStream(steps...), nil
+	}
+	return nil, errors.Errorf("Updater.Type: unknown bit stream %q, expected one of linear, looping, random, static, choreography or lanes", u.Type)
+}
+
+func (u updaterSpec) buildLanes() (BitStreamUpdater, error) {
+	if len(u.Lanes) == 0 {
+		return nil, errors.New("Updater.Lanes: lanes bit stream needs at least one lane")
+	}
+	lanes := make([]bitStreamLane, 0, len(u.Lanes))
+	columns := u.Lanes[0].Dir == MoveUp || u.Lanes[0].Dir == MoveDown
+	for i, lane := range u.Lanes {
+		switch {
+		case lane.Dir != MoveUp && lane.Dir != MoveDown && lane.Dir != MoveLeft && lane.Dir != MoveRight:
+			return nil, errors.Errorf("Updater.Lanes: [%d]: lanes can't go %s", i, directionNames[lane.Dir])
+		case columns != (lane.Dir == MoveUp || lane.Dir == MoveDown):
+			return nil, errors.Errorf("Updater.Lanes: [%d]: lanes have to be all rows going Left or Right, or all columns going Up or Down", i)
+		case lane.Speed < 0:
+			return nil, errors.Errorf("Updater.Lanes: [%d]: Speed can't be negative", i)
+		}
+		lanes = append(lanes, bitStreamLane{dir: lane.Dir, movement: movement{speed: lane.Speed}})
+	}
+	return newLaneBitStream(columns, lanes...), nil
+}
+
+// generatorSpec describes a BitGenerator.
+type generatorSpec struct {
+	Type   string  // one of random, noise, packets or walls
+	Seed   int64   // seeds the noise for noise
+	Size   float64 // how many cells across the clumps are for noise, or the packets are for packets
+	Drift  float64 // how fast the clumps change shape for noise
+	Chance float32 // the chance each time the bit stream moves of a new packet for packets, or a wall for walls
+	Gap    int     // how wide the gap in each wall is for walls
+}
+
+func (g generatorSpec) build() (BitGenerator, error) {
+	switch strings.ToLower(g.Type) {
+	case "random":
+		return nil, nil
+	case "noise":
+		if g.Size <= 0 {
+			return nil, errors.New("BitGenerator.Size: noise needs a Size")
+		}
+		return newNoiseBits(g.Seed, g.Size, g.Drift), nil
+	case "packets":
+		if g.Size < 1 {
+			return nil, errors.New("BitGenerator.Size: packets need a Size of at least 1")
+		}
+		if g.Chance <= 0 || g.Chance > 1 {
+			return nil, errors.New("BitGenerator.Chance: packets need a Chance between 0 and 1")
+		}
+		return newPacketBits(g.Chance, int(g.Size)), nil
+	case "walls":
+		if g.Gap < 1 {
+			return nil, errors.New("BitGenerator.Gap: walls need a Gap of at least 1")
+		}
+		if g.Chance <= 0 || g.Chance > 1 {
+			return nil, errors.New("BitGenerator.Chance: walls need a Chance between 0 and 1")
+		}
+		return newWallBits(g.Chance, g.Gap), nil
+	}
+	return nil, errors.Errorf("BitGenerator.Type: unknown bit generator %q, expected one of random, noise, packets or walls", g.Type)
+}
+
+// exitSpec describes an ExitPlacement.
+type exitSpec struct {
+	Type string // one of Fixed, Farthest, Edge, NearGuard or Anywhere
+	X, Y int    // where the exit goes for Fixed
+}
+
+func (e exitSpec) build() (ExitPlacement, error) {
+	switch strings.ToLower(e.Type) {
+	case "fixed":
+		return ExitAt{X: e.X, Y: e.Y}, nil
+	case "farthest":
+		return ExitFarthest{}, nil
+	case "edge":
+		return ExitOnEdge{}, nil
+	case "nearguard":
+		return ExitNearGuard{}, nil
+	case "anywhere":
+		return nil, nil
+	}
+	return nil, errors.Errorf("ExitPlacement.Type: unknown exit placement %q, expected one of Fixed, Farthest, Edge, NearGuard or Anywhere", e.Type)
+}
+
+// winConditionSpec describes a WinCondition. Conditions without a type are for collecting data.
+type winConditionSpec struct {
+	Type       string             // one of Data, Survive, ThreatUnder, CollectRarity, CollectAll, AllOf or AnyOf
+	Kind       DataKind           // the kind of data for Data and CollectAll
+	Amount     float32            // how much data for Data
+	Duration   duration           // how long to survive for Survive
+	Percent    float32            // the most threat the player can leave with for ThreatUnder
+	Rarity     Rarity             // how rare the loot needs to be for CollectRarity
+	Count      int                // how much loot for CollectRarity
+	Conditions []winConditionSpec // the conditions for AllOf and AnyOf
+}
+
+func buildWinConditions(specs []winConditionSpec) ([]WinCondition, error) {
+	conditions := make([]WinCondition, 0, len(specs))
+	for i, spec := range specs {
+		cond, err := spec.build()
+		if err != nil {
+			return nil, errors.Wrapf(err, "[%d]", i)
+		}
+		conditions = append(conditions, cond)
+	}
+	return conditions, nil
+}
+
+func (w winConditionSpec) build() (WinCondition, error) {
+	switch strings.ToLower(w.Type) {
+	case "", "data":
+		if w.Kind == DataKindNone || w.Amount <= 0 {
+			return nil, errors.New("Data needs a Kind and an Amount")
+		}
+		return CollectData{Kind: w.Kind, Amount: w.Amount}, nil
+	case "survive":
+		if w.Duration <= 0 {
+			return nil, errors.New("Survive needs a Duration")
+		}
+		return SurviveFor{Duration: time.Duration(w.Duration)}, nil
+	case "threatunder":
+		if w.Percent <= 0 || w.Percent > 100 {
+			return nil, errors.New("ThreatUnder needs a Percent between 0 and 100")
+		}
+		return ThreatUnder{Percent: w.Percent}, nil
+	case "collectrarity":
+		if w.Count <= 0 {
+			return nil, errors.New("CollectRarity needs a Count")
+		}
+		return CollectRarity{Rarity: w.Rarity, Count: w.Count}, nil
+	case "collectall":
+		if w.Kind == DataKindNone {
+			return nil, errors.New("CollectAll needs a Kind")
+		}
+		return CollectAll{Kind: w.Kind}, nil
+	case "allof", "anyof":
+		if len(w.Conditions) == 0 {
+			return nil, errors.Errorf("%s needs some Conditions", w.Type)
+		}
+		conditions, err := buildWinConditions(w.Conditions)
+		if err != nil {
+			return nil, errors.Wrap(err, "Conditions")
+		}
+		if strings.ToLower(w.Type) == "allof" {
+			return AllOf(conditions), nil
+		}
+		return AnyOf(conditions), nil
+	}
+	return nil, errors.Errorf("unknown win condition %q, expected one of Data, Survive, ThreatUnder, CollectRarity, CollectAll, AllOf or AnyOf", w.Type)
+}
+
+// loseConditionSpec describes a LoseCondition.
+type loseConditionSpec struct {
+	Type     string   // one of TimeLimit, DataLost or ThreatLimit
+	Duration duration // how long the player has for TimeLimit
+	Kind     DataKind // the kind of data that can't be lost for DataLost
+	Percent  float32  // the most threat the player can have for ThreatLimit
+}
+
+func (l loseConditionSpec) build() (LoseCondition, error) {
+	switch strings.ToLower(l.Type) {
+	case "timelimit":
+		if l.Duration <= 0 {
+			return nil, errors.New("TimeLimit needs a Duration")
+		}
+		return TimeLimit{Duration: time.Duration(l.Duration)}, nil
+	case "datalost":
+		if l.Kind == DataKindNone {
+			return nil, errors.New("DataLost needs a Kind")
+		}
+		return DataLost{Kind: l.Kind}, nil
+	case "threatlimit":
+		if l.Percent < 0 || l.Percent >= 100 {
+			return nil, errors.New("ThreatLimit needs a Percent from 0 up to 100")
+		}
+		return ThreatLimit{Percent: l.Percent}, nil
+	}
+	return nil, errors.Errorf("unknown lose condition %q, expected one of TimeLimit, DataLost or ThreatLimit", l.Type)
+}
+
+// duration is written as a string like "10s" or "1m30s" in level files.
+type duration time.Duration
+
+func (d *duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return errors.Errorf("expected a duration like \"10s\", got %s", b)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = duration(v)
+	return nil
+}
+
+func (d duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// levelSource is the contents of a level file.
+type levelSource struct {
+	path string
+	data []byte
+}
+
+// LoadLevel reads a level config from a JSON or YAML file.
+func LoadLevel(path string) (LevelConfig, error) {
+	src, err := readLevelSource(path)
+	if err != nil {
+		return LevelConfig{}, err
+	}
+	return src.parse()
+}
+
+// LoadCampaign loads every level file in dir. They're played in order of their file names, unless there's a campaign
+// file in dir that says otherwise.
+func LoadCampaign(dir string) (Campaign, error) {
+	paths, err := LevelFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, errors.Errorf("no level files found in %s", dir)
+	}
+	campaign := &fileCampaign{}
+	for _, path := range paths {
+		src, err := readLevelSource(path)
+		if err != nil {
+			return nil, err
+		}
+		l, err := src.parse()
+		if err != nil {
+			return nil, err
+		}
+		if errs := l.Validate(); len(errs) > 0 {
+			return nil, errors.Errorf("level %s is not valid: %v", path, errs)
+		}
+		campaign.levels = append(campaign.levels, src)
+	}
+	if err := campaign.loadGraph(dir); err != nil {
+		return nil, err
+	}
+	return campaign, nil
+}
+
+// LevelFiles returns the paths of all the level files in dir, sorted by name.
+func LevelFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading levels from %s", dir)
+	}
+	var paths []string
+	for _, entry := range entries {
+		if !entry.IsDir() && isLevelFile(entry.Name()) && !isCampaignFile(entry.Name()) {
+			paths = append(paths, filepath.Join(dir, entry.Name()))
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func isLevelFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json", ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+func isCampaignFile(path string) bool {
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)) == campaignFileName
+}
+
+func readLevelSource(path string) (levelSource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return levelSource{}, errors.Wrapf(err, "reading level %s", path)
+	}
+	return levelSource{path: path, data: data}, nil
+}
+
+func (src levelSource) parse() (LevelConfig, error) {
+	l, err := parseLevel(src.path, src.data)
+	if err != nil {
+		return l, errors.Wrapf(err, "loading level %s", src.path)
+	}
+	if l.Title == "" {
+		l.Title = strings.TrimSuffix(filepath.Base(src.path), filepath.Ext(src.path))
+	}
+	return l, nil
+}
+
+// toJSON converts YAML to JSON, so both formats can be decoded the same way.
+func toJSON(path string, data []byte) ([]byte, error) {
+	if ext := strings.ToLower(filepath.Ext(path)); ext != ".yaml" && ext != ".yml" {
+		return data, nil
+	}
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+func parseLevel(path string, data []byte) (LevelConfig, error) {
+	data, err := toJSON(path, data)
+	if err != nil {
+		return LevelConfig{}, err
+	}
+	return parseLevelJSON(path, data)
+}
+
+// parseLevelJSON decodes a level that's already been converted to JSON. The path is only used to find layout files.
+func parseLevelJSON(path string, data []byte) (LevelConfig, error) {
+	// Decode one field at a time, so that any errors can say exactly which field is wrong.
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return LevelConfig{}, err
+	}
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	l := defaultLevel()
+	file := levelFile{LevelConfig: &l}
+	value := reflect.ValueOf(&file).Elem()
+	for _, name := range names {
+		field := value.FieldByName(name)
+		if !field.IsValid() || !field.CanSet() || name == "LevelConfig" {
+			return l, errors.Errorf("%s: unknown field", name)
+		}
+		decoder := json.NewDecoder(bytes.NewReader(fields[name]))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(field.Addr().Interface()); err != nil {
+			return l, errors.Wrap(err, name)
+		}
+	}
+
+	for kind, d := range file.PowerUpDuration {
+		l.PowerUpDuration[kind] = time.Duration(d)
+	}
+	if file.HitInvulnerability != nil {
+		l.HitInvulnerability = time.Duration(*file.HitInvulnerability)
+	}
+	if file.HitFlashRate != nil {
+		l.HitFlashRate = time.Duration(*file.HitFlashRate)
+	}
+	if file.Updater != nil {
+		updater, err := file.Updater.build()
+		if err != nil {
+			return l, err
+		}
+		l.Updater = updater
+	}
+	if file.BitGenerator != nil {
+		generator, err := file.BitGenerator.build()
+		if err != nil {
+			return l, err
+		}
+		l.BitGenerator = generator
+	}
+	if file.ExitPlacement != nil {
+		placement, err := file.ExitPlacement.build()
+		if err != nil {
+			return l, err
+		}
+		l.ExitPlacement = placement
+	}
+	if file.WinConditions != nil {
+		conditions, err := buildWinConditions(file.WinConditions)
+		if err != nil {
+			return l, errors.Wrap(err, "WinConditions")
+		}
+		l.WinConditions = conditions
+	}
+	for i, spec := range file.LoseConditions {
+		cond, err := spec.build()
+		if err != nil {
+			return l, errors.Wrapf(err, "LoseConditions: [%d]", i)
+		}
+		l.LoseConditions = append(l.LoseConditions, cond)
+	}
+	if file.Layout != "" {
+		layout, err := loadLayout(path, file.Layout)
+		if err != nil {
+			return l, errors.Wrap(err, "Layout")
+		}
+		layout.apply(&l)
+	}
+	return l, nil
+}
+
+func loadLayout(levelPath, value string) (*Layout, error) {
+	if strings.Contains(value, "\n") {
+		return ParseLayout([]byte(value))
+	}
+	path := value
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(levelPath), path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseLayout(data)
+}
+
+// Enums are written by name in level files.
+var (
+	rarityNames    = []string{"Junk", "Common", "Uncommon", "Rare", "Epic", "Legendary"}
+	dataKindNames  = []string{"None", "Delta", "Lambda", "Sigma", "Omega"}
+	powerUpNames   = []string{"None", "VisionRange", "ThreatDecay", "BadBitImmunity", "BadBitsAreGood", "LootSpeed"}
+	itemKindNames  = []string{"None", "Pylon", "Portal"}
+	directionNames = []string{"Up", "Down", "Left", "Right", "UpLeft", "UpRight", "DownLeft", "DownRight"}
+	levelKindNames = []string{"Firewall", "Mainframe"}
+)
+
+func marshalEnum(what string, value int, names []string) ([]byte, error) {
+	if value < 0 || value >= len(names) {
+		return nil, errors.Errorf("unknown %s %d", what, value)
+	}
+	return []byte(names[value]), nil
+}
+
+func unmarshalEnum(what string, text []byte, names []string) (int, error) {
+	for i, name := range names {
+		if strings.EqualFold(name, string(text)) {
+			return i, nil
+		}
+	}
+	return 0, errors.Errorf("unknown %s %q, expected one of %s", what, text, strings.Join(names, ", "))
+}
+
+func (r Rarity) MarshalText() ([]byte, error) {
+	return marshalEnum("rarity", int(r), rarityNames)
+}
+
+func (r *Rarity) UnmarshalText(text []byte) error {
+	v, err := unmarshalEnum("rarity", text, rarityNames)
+	*r = Rarity(v)
+	return err
+}
+
+func (k DataKind) MarshalText() ([]byte, error) {
+	return marshalEnum("data kind", int(k), dataKindNames)
+}
+
+func (k *DataKind) UnmarshalText(text []byte) error {
+	v, err := unmarshalEnum("data kind", text, dataKindNames)
+	*k = DataKind(v)
+	return err
+}
+
+func (k PowerUpKind) MarshalText() ([]byte, error) {
+	return marshalEnum("power up", int(k), powerUpNames)
+}
+
+func (k *PowerUpKind) UnmarshalText(text []byte) error {
+	v, err := unmarshalEnum("power up", text, powerUpNames)
+	*k = PowerUpKind(v)
+	return err
+}
+
+func (k ItemKind) MarshalText() ([]byte, error) {
+	return marshalEnum("item", int(k), itemKindNames)
+}
+
+func (k *ItemKind) UnmarshalText(text []byte) error {
+	v, err := unmarshalEnum("item", text, itemKindNames)
+	*k = ItemKind(v)
+	return err
+}
+
+func (d Direction) MarshalText() ([]byte, error) {
+	return marshalEnum("direction", int(d), directionNames)
+}
+
+func (d *Direction) UnmarshalText(text []byte) error {
+	v, err := unmarshalEnum("direction", text, directionNames)
+	*d = Direction(v)
+	return err
+}
+
+func (k LevelKind) MarshalText() ([]byte, error) {
+	return marshalEnum("level kind", int(k), levelKindNames)
+}
+
+func (k *LevelKind) UnmarshalText(text []byte) error {
+	v, err := unmarshalEnum("level kind", text, levelKindNames)
+	*k = LevelKind(v)
+	return err
+}
@@ -0,0 +1,155 @@
+package zen_doctor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadCampaign(t *testing.T) {
+	campaign, err := LoadCampaign("../levels")
+	require.NoError(t, err)
+
+	first, ok := campaign.Get(campaign.First())
+	require.True(t, ok)
+	assert.Equal(t, "Warmup", first.Name())
+	assert.Equal(t, float32(1.5), first.FPS)
+	assert.Equal(t, []Wall{{X: 40, Y: 6, Width: 3, Height: 8}}, first.Walls)
+	assert.IsType(t, &linearBitStream{}, first.Updater)
+
+	next := campaign.Next(campaign.First(), Progress{})
+	require.Len(t, next, 1)
+	second, ok := campaign.Get(next[0].Level)
+	require.True(t, ok)
+	assert.Equal(t, "Crossfire", second.Name())
+	assert.Equal(t, float32(25), second.ThreatByRarity[Legendary])
+	assert.Equal(t, float32(15), second.ThreatByRarity[Epic], "maps are merged with the defaults")
+	assert.Equal(t, lootTable{{Data: DataKindDelta, Chance: 0.5}, {Data: DataKindLambda, Chance: 0.5}}, second.DataLootTable)
+	assert.Equal(t, 30*time.Second, second.PowerUpDuration[PowerUpVisionRange])
+	assert.Equal(t, 500*time.Millisecond, second.HitInvulnerability)
+	assert.Equal(t, 125*time.Millisecond, second.HitFlashRate)
+	assert.IsType(t, &bitStreamWithSteps{}, second.Updater)
+
+	next = campaign.Next(second.Level, Progress{})
+	require.Len(t, next, 1)
+	third, ok := campaign.Get(next[0].Level)
+	require.True(t, ok)
+	assert.Equal(t, "Sluice", third.Name())
+	require.NotNil(t, third.Layout, "layout is loaded from the maps directory")
+	assert.Equal(t, 60, third.Width, "level is the size of the layout")
+	assert.Equal(t, 14, third.Height)
+	assert.Len(t, third.Layout.Spawns, 2)
+	assert.IsType(t, &choreography{}, third.Updater)
+
+	next = campaign.Next(third.Level, Progress{})
+	require.Len(t, next, 1)
+	fourth, ok := campaign.Get(next[0].Level)
+	require.True(t, ok)
+	assert.Equal(t, "Traffic", fourth.Name())
+	require.IsType(t, &laneBitStream{}, fourth.Updater)
+	assert.Len(t, fourth.Updater.(*laneBitStream).lanes, 5)
+
+	assert.Empty(t, campaign.Next(fourth.Level, Progress{}), "campaign ends after the last file")
+}
+
+func TestLoadLevelErrors(t *testing.T) {
+	tests := map[string]struct {
+		file string
+		want string
+	}{
+		"unknown field":     {`{"Widht": 10}`, "Widht: unknown field"},
+		"wrong type":        {`{"FPS": "fast"}`, "FPS: "},
+		"unknown enum":      {`{"WinConditions": [{"Kind": "Gamma", "Amount": 1}]}`, `WinConditions: unknown data kind "Gamma"`},
+		"nested field":      {`{"Walls": [{"X": 1, "Wide": 2}]}`, `Walls: json: unknown field "Wide"`},
+		"bad duration":      {`{"PowerUpDuration": {"LootSpeed": 10}}`, "PowerUpDuration: "},
+		"unknown updater":   {`{"Updater": {"Type": "sideways"}}`, `Updater.Type: unknown bit stream "sideways"`},
+		"updater no steps":  {`{"Updater": {"Type": "looping"}}`, "Updater.Steps: "},
+		"unknown condition": {`{"WinConditions": [{"Type": "Escape"}]}`, `WinConditions: [0]: unknown win condition "Escape"`},
+		"nested condition":  {`{"WinConditions": [{"Type": "AllOf", "Conditions": [{"Type": "Survive"}]}]}`, "WinConditions: [0]: Conditions: [0]: Survive needs a Duration"},
+		"unknown lose":      {`{"LoseConditions": [{"Type": "Boredom"}]}`, `LoseConditions: [0]: unknown lose condition "Boredom"`},
+		"time limit":        {`{"LoseConditions": [{"Type": "TimeLimit"}]}`, "LoseConditions: [0]: TimeLimit needs a Duration"},
+		"unknown exit":      {`{"ExitPlacement": {"Type": "Window"}}`, `ExitPlacement.Type: unknown exit placement "Window"`},
+		"bad choreography":  {`{"Updater": {"Type": "choreography", "Script": "down 1s\nup"}}`, "Updater.Script: line 2: expected a duration"},
+		"lanes no lanes":    {`{"Updater": {"Type": "lanes"}}`, "Updater.Lanes: lanes bit stream needs at least one lane"},
+		"diagonal lane":     {`{"Updater": {"Type": "lanes", "Lanes": [{"Dir": "UpLeft", "Speed": 1}]}}`, "Updater.Lanes: [0]: lanes can't go UpLeft"},
+		"mixed lanes":       {`{"Updater": {"Type": "lanes", "Lanes": [{"Dir": "Left"}, {"Dir": "Up"}]}}`, "Updater.Lanes: [1]: lanes have to be all rows"},
+		"unknown generator": {`{"BitGenerator": {"Type": "lumpy"}}`, `BitGenerator.Type: unknown bit generator "lumpy"`},
+		"wall without gap":  {`{"BitGenerator": {"Type": "walls", "Chance": 0.1}}`, "BitGenerator.Gap: walls need a Gap"},
+		"updater bad field": {`{"Updater": {"Type": "linear", "Direction": "Up"}}`, `Updater: json: unknown field "Direction"`},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "level.json")
+			require.NoError(t, os.WriteFile(path, []byte(tc.file), 0644))
+
+			_, err := LoadLevel(path)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.want)
+		})
+	}
+}
+
+func TestLoadWinConditions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "level.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+WinConditions:
+  - {Kind: Delta, Amount: 100}
+  - Type: AnyOf
+    Conditions:
+      - {Type: Survive, Duration: 2m}
+      - {Type: CollectRarity, Rarity: Epic, Count: 2}
+      - Type: AllOf
+        Conditions:
+          - {Type: CollectAll, Kind: Delta}
+          - {Type: ThreatUnder, Percent: 25}
+`), 0644))
+
+	level, err := LoadLevel(path)
+	require.NoError(t, err)
+	assert.Equal(t, []WinCondition{
+		CollectData{Kind: DataKindDelta, Amount: 100},
+		AnyOf{
+			SurviveFor{Duration: 2 * time.Minute},
+			CollectRarity{Rarity: Epic, Count: 2},
+			AllOf{CollectAll{Kind: DataKindDelta}, ThreatUnder{Percent: 25}},
+		},
+	}, level.WinConditions)
+}
+
+func TestLoadLoseConditions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "level.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+LoseConditions:
+  - {Type: TimeLimit, Duration: 90s}
+  - {Type: DataLost, Kind: Omega}
+  - {Type: ThreatLimit, Percent: 75}
+`), 0644))
+
+	level, err := LoadLevel(path)
+	require.NoError(t, err)
+	assert.Equal(t, []LoseCondition{
+		TimeLimit{Duration: 90 * time.Second},
+		DataLost{Kind: DataKindOmega},
+		ThreatLimit{Percent: 75},
+	}, level.LoseConditions)
+}
+
+func TestParseLayout(t *testing.T) {
+	layout, err := ParseLayout([]byte("###.\n#@D*\n E I\n\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 4, layout.Width)
+	assert.Equal(t, 3, layout.Height, "blank lines at the end don't count")
+	assert.Equal(t, []Wall{{X: 0, Y: 0, Width: 3, Height: 1}, {X: 0, Y: 1, Width: 1, Height: 1}}, layout.Walls)
+	assert.Equal(t, map[Coordinate]LootKind{{2, 1}: LootData, {3, 2}: LootItem}, layout.LootSpots)
+	assert.Equal(t, []Coordinate{{1, 1}}, layout.Spawns)
+	assert.Equal(t, []Coordinate{{1, 2}}, layout.Exits)
+	assert.Equal(t, []Coordinate{{3, 1}}, layout.Emitters)
+
+	_, err = ParseLayout([]byte("..\n.x"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 2, column 2: unknown map character 'x'")
+}
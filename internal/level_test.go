@@ -3,6 +3,7 @@ package zen_doctor
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -15,57 +16,95 @@ func TestLevelConfigs(t *testing.T) {
 			// level name is correct
 			assert.Equal(t, i, level.Level, "Level index must match")
 
-			// each level must have an updater and win conditions defined
-			assert.NotNil(t, level.Updater, "must have updater")
-			assert.NotEmpty(t, level.WinConditions, "must have win conditions")
-			assert.NotEmpty(t, level.PowerUpLootTable, "must have power ups")
-			assert.NotEmpty(t, level.DataLootTable, "must have data")
+			// and it follows all the rules
+			assert.Empty(t, level.Validate())
+		})
+	}
+}
 
-			// The sum of the loot tables must be 1
-			sum := float32(0.0)
-			for _, entry := range level.DataLootTable {
-				sum += entry.Chance
-			}
-			assert.Equal(t, float32(1.0), sum, "sum of chances in DataLootTable must equal 1")
+func TestValidate(t *testing.T) {
+	tests := map[string]struct {
+		change func(l *LevelConfig)
+		want   string
+	}{
+		"no updater":        {func(l *LevelConfig) { l.Updater = nil }, "must have updater"},
+		"no win conditions": {func(l *LevelConfig) { l.WinConditions = nil }, "must have win conditions"},
+		"loot table sum":    {func(l *LevelConfig) { l.PowerUpLootTable[0].Chance = 0.5 }, "sum of chances in PowerUpLootTable must equal 1"},
+		"loot table chance": {func(l *LevelConfig) {
+			l.DataLootTable = lootTable{{Data: DataKindDelta, Chance: 1.5}, {Data: DataKindDelta, Chance: -0.5}}
+		}, "chance for DataLootTable[0] must be between 0 and 1"},
+		"missing win loot": {func(l *LevelConfig) {
+			l.WinConditions = []WinCondition{AnyOf{CollectData{Kind: DataKindOmega, Amount: 1}}}
+		}, "missing Omega"},
+		"positive decay": {func(l *LevelConfig) { l.ThreatDecay = 0.1 }, "ThreatDecay must be negative"},
+		"no width":       {func(l *LevelConfig) { l.Width = 0 }, "Width must be at least 3"},
+		"no room": {func(l *LevelConfig) {
+			l.Walls = []Wall{{X: 0, Y: 0, Width: l.Width - 1, Height: l.Height - 1}}
+		}, "walls must leave an open cell the player can get to"},
+		"too narrow": {func(l *LevelConfig) { l.Width = 2 }, "Width must be at least 3"},
+		"too short":  {func(l *LevelConfig) { l.Height = 2 }, "Height must be at least 3"},
+		"too small to patrol": {func(l *LevelConfig) {
+			l.Width, l.EnemyCount, l.PatrolRoutes = 2, 1, nil
+		}, "EnemyCount without PatrolRoutes needs a level at least 3x3"},
+		"zero multiplier":          {func(l *LevelConfig) { l.DataMultipliers[DataKindSigma] = 0 }, "Data multiplier for Sigma must be positive"},
+		"bit chance too high":      {func(l *LevelConfig) { l.BadBitChance = 2 }, "BadBitChance must be between 0 and 1"},
+		"negative invulnerability": {func(l *LevelConfig) { l.HitInvulnerability = -time.Second }, "HitInvulnerability can't be negative"},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			level := defaultLevel()
+			tc.change(&level)
 
-			sum = float32(0.0)
-			for _, entry := range level.PowerUpLootTable {
-				sum += entry.Chance
+			errs := level.Validate()
+			messages := make([]string, 0, len(errs))
+			for _, err := range errs {
+				messages = append(messages, err.Error())
 			}
-			assert.Equal(t, float32(1.0), sum, "sum of chances in PowerUpLootTable must equal 1")
+			assert.Contains(t, fmt.Sprint(messages), tc.want)
+		})
+	}
+}
 
-			// Loot exists in the loot table for all win conditions
-			for _, cond := range level.WinConditions {
-				found := false
-				for _, loot := range level.DataLootTable {
-					if cond.Kind == loot.Data {
-						found = true
-					}
-				}
-				assert.True(t, found, "must include loot table for all win conditions")
-			}
+func TestGenerateLevel(t *testing.T) {
+	// the same seed and depth always make the same level
+	a, b := GenerateLevel(42, 12), GenerateLevel(42, 12)
+	assert.Equal(t, a.Walls, b.Walls)
+	assert.Equal(t, a.WinConditions, b.WinConditions)
+	assert.Equal(t, a.Updater.(*bitStreamWithSteps).steps, b.Updater.(*bitStreamWithSteps).steps)
+	assert.NotEqual(t, a.Walls, GenerateLevel(43, 12).Walls, "different seeds should make different levels")
 
-			// all decay values should be negative
-			assert.True(t, 0 > level.LeaveSpeedDecay, "LeaveSpeedDecay must be negative")
-			assert.True(t, 0 > level.DataDecayRate, "DataDecayRate must be negative")
-			assert.True(t, 0 > level.FootprintDecay, "FootprintDecay must be negative")
-			assert.True(t, 0 > level.ThreatDecay, "ThreatDecay must be negative")
-			assert.True(t, 0 > level.LootSpeedDecay, "LootSpeedDecay must be negative")
-			assert.True(t, 0 > level.PowerUpDecayRate, "LootSpeedDecay must be negative")
+	// the shallow end is easy, but still has to play
+	for depth := 0; depth < endlessStartDepth; depth++ {
+		level := GenerateLevel(42, depth)
+		assert.Empty(t, level.Validate(), "depth %d", depth)
+		e := newTestEngine(level, Coordinate{10, 10})
+		assert.NotPanics(t, func() { e.Step(10 * time.Second) }, "depth %d", depth)
+	}
 
-			// width and height must be defined
-			assert.True(t, level.Width > 0, "Width must be positive")
-			assert.True(t, level.Height > 0, "Height must be positive")
+	previous := GetLevel(Level5)
+	for depth := endlessStartDepth; depth < 50; depth++ {
+		level := GenerateLevel(42, depth)
+		assert.Empty(t, level.Validate(), "depth %d", depth)
 
-			// Data multipliers should be non-zero
-			for _, multiplier := range level.DataMultipliers {
-				assert.True(t, multiplier > 0, "Data multiplier must be positive")
-			}
+		// and each level is harder than the one before it
+		assert.Greater(t, level.FPS, previous.FPS, "depth %d", depth)
+		assert.Greater(t, level.BitStreamChance, previous.BitStreamChance, "depth %d", depth)
+		assert.Greater(t, level.WinConditions[0].(CollectData).Amount, previous.WinConditions[0].(CollectData).Amount, "depth %d", depth)
+		previous = level
+	}
+}
 
-			// Bit stream chances must be between 0 and 1
-			assert.True(t, 0 <= level.BitStreamChance && level.BitStreamChance <= 1, "BitStreamChance must be between 0 and 1")
-			assert.True(t, 0 <= level.BadBitChance && level.BadBitChance <= 1, "BadBitChance must be between 0 and 1")
-			assert.True(t, 0 <= level.GoodBitChance && level.GoodBitChance <= 1, "GoodBitChance must be between 0 and 1")
-		})
+func TestEndlessCampaign(t *testing.T) {
+	campaign := NewEndlessCampaign(DefaultCampaign, 1)
+	level := campaign.First()
+	for i := 0; i < 20; i++ {
+		config, ok := campaign.Get(level)
+		assert.True(t, ok, "level %d", i)
+		assert.Equal(t, level, config.Level)
+		next := campaign.Next(level, Progress{})
+		assert.Len(t, next, 1)
+		level = next[0].Level
 	}
+	config, _ := campaign.Get(level)
+	assert.Equal(t, "Level 18", config.Name())
 }
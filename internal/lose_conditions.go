@@ -0,0 +1,60 @@
+package zen_doctor
+
+import (
+	"fmt"
+	"time"
+)
+
+// LoseCondition is a way to fail a level, besides getting caught.
+type LoseCondition interface {
+	IsFailed(s *GameState) bool
+	// Describe explains what went wrong, for the game over screen.
+	Describe(mode CompatibilityMode) string
+}
+
+// TimeLimit fails once the player has been in the level for too long.
+type TimeLimit struct {
+	Duration time.Duration
+}
+
+func (c TimeLimit) IsFailed(s *GameState) bool {
+	return c.left(s) <= 0
+}
+
+func (c TimeLimit) left(s *GameState) time.Duration {
+	return c.Duration - s.timeInLevel()
+}
+
+func (c TimeLimit) Describe(_ CompatibilityMode) string {
+	return fmt.Sprintf("Time ran out after %s!", ElapsedTime(c.Duration))
+}
+
+// DataLost fails if any data of a kind decays away before the player can loot it.
+type DataLost struct {
+	Kind DataKind
+}
+
+func (c DataLost) IsFailed(s *GameState) bool {
+	return s.world.Despawned[c.Kind] > 0
+}
+
+func (c DataLost) Describe(mode CompatibilityMode) string {
+	return fmt.Sprintf("Some %s decayed before you could loot it!", c.Kind.ForMode(mode))
+}
+
+// ThreatLimit fails as soon as the player's threat goes over a percentage of the max. With a Percent of 0, any
+// threat at all fails the level - for a truly threat-free run, the level shouldn't have any MovementThreat either.
+type ThreatLimit struct {
+	Percent float32
+}
+
+func (c ThreatLimit) IsFailed(s *GameState) bool {
+	return s.threatPercent() > c.Percent
+}
+
+func (c ThreatLimit) Describe(_ CompatibilityMode) string {
+	if c.Percent == 0 {
+		return "You weren't supposed to be noticed at all!"
+	}
+	return fmt.Sprintf("Your threat went over %.0f%%!", c.Percent)
+}
@@ -0,0 +1,61 @@
+package zen_doctor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoseConditions(t *testing.T) {
+	s := NewGameState(GetLevel(Tutorial), CompatibilityAscii, testRand(), SystemClock)
+	s.world.Despawned[DataKindDelta] = 1
+	s.player.Threat = s.level.MaxThreat / 2
+	s.started = time.Now().Add(-30 * time.Second)
+
+	tests := map[string]struct {
+		cond   LoseCondition
+		failed bool
+	}{
+		"out of time":      {TimeLimit{Duration: 20 * time.Second}, true},
+		"still time":       {TimeLimit{Duration: time.Minute}, false},
+		"data lost":        {DataLost{Kind: DataKindDelta}, true},
+		"data not lost":    {DataLost{Kind: DataKindOmega}, false},
+		"too much threat":  {ThreatLimit{Percent: 40}, true},
+		"threat in limits": {ThreatLimit{Percent: 60}, false},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.failed, tc.cond.IsFailed(&s))
+			assert.NotEmpty(t, tc.cond.Describe(CompatibilityAscii))
+		})
+	}
+}
+
+func TestLoseConditionEndsGame(t *testing.T) {
+	level := GetLevel(Tutorial)
+	level.LoseConditions = []LoseCondition{ThreatLimit{Percent: 50}, TimeLimit{Duration: time.Minute}, TimeLimit{Duration: 2 * time.Minute}}
+	s := NewGameState(level, CompatibilityAscii, testRand(), SystemClock)
+
+	left, ok := s.TimeLeft()
+	assert.True(t, ok)
+	assert.InDelta(t, time.Minute, left, float64(time.Second), "the shortest limit counts")
+
+	s.TickWorld()
+	assert.False(t, s.IsGameOver())
+	assert.Empty(t, s.LoseReason())
+
+	s.player.Threat = level.MaxThreat * 0.9
+	s.TickWorld()
+	assert.True(t, s.IsGameOver())
+	assert.True(t, s.IsFailed())
+	assert.Equal(t, "Your threat went over 50%!", s.LoseReason())
+}
+
+func TestDataLostWhenLootDecays(t *testing.T) {
+	s := NewGameState(GetLevel(Tutorial), CompatibilityAscii, testRand(), SystemClock)
+	s.world.Loot = map[Coordinate]Loot{{1, 1}: {Kind: LootData, DataKind: DataKindSigma, Integrity: 0.0001}}
+	s.world.TickLoot()
+	assert.Equal(t, 1, s.world.Despawned[DataKindSigma])
+	assert.True(t, DataLost{Kind: DataKindSigma}.IsFailed(&s))
+}
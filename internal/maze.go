@@ -0,0 +1,64 @@
+package zen_doctor
+
+import "math/rand"
+
+// how many of the remaining walls inside the maze get knocked down, so there's more than one way around.
+const mazeLoopChance = 0.08
+
+// newMaze carves a maze out of a grid full of walls, and returns the walls that are left over.
+// Passages are on odd coordinates, so the outside of the map is always a wall.
+func newMaze(width, height int, seed int64) map[Coordinate]bool {
+	rng := rand.New(rand.NewSource(seed))
+	walls := make(map[Coordinate]bool)
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			walls[Coordinate{x, y}] = true
+		}
+	}
+	isCell := func(c Coordinate) bool {
+		return c.X > 0 && c.X < width-1 && c.Y > 0 && c.Y < height-1 && c.X%2 == 1 && c.Y%2 == 1
+	}
+
+	// recursive backtracker, without the recursion
+	start := Coordinate{1, 1}
+	walls[start] = false
+	stack := []Coordinate{start}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		var options []Coordinate
+		for d := MoveUp; d <= MoveRight; d++ {
+			dx, dy := d.Delta()
+			next := Coordinate{current.X + 2*dx, current.Y + 2*dy}
+			if isCell(next) && walls[next] {
+				options = append(options, next)
+			}
+		}
+		if len(options) == 0 {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		next := options[rng.Intn(len(options))]
+		walls[Coordinate{(current.X + next.X) / 2, (current.Y + next.Y) / 2}] = false
+		walls[next] = false
+		stack = append(stack, next)
+	}
+
+	// knock down a few walls between passages to make some loops
+	for x := 1; x < width-2; x++ {
+		for y := 1; y < height-2; y++ {
+			c := Coordinate{x, y}
+			horizontal := x%2 == 0 && y%2 == 1
+			vertical := x%2 == 1 && y%2 == 0
+			if walls[c] && (horizontal || vertical) && rng.Float32() < mazeLoopChance {
+				walls[c] = false
+			}
+		}
+	}
+
+	for c, wall := range walls {
+		if !wall {
+			delete(walls, c)
+		}
+	}
+	return walls
+}
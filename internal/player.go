@@ -9,13 +9,18 @@ type Player struct {
 	Location      Coordinate
 	Threat        float32
 	Inventory     []Loot
+	Items         []Item
 	CurrentAction playerAction
 	DataCollected map[DataKind]float32
+	Effects       activeEffects
 
 	// movement support
 	lastInput time.Time
 	direction Direction
 	automove  bool
+
+	// bad bits pass through the player until then, after one hits them
+	invulnerableUntil time.Time
 }
 
 type ActionType int
@@ -72,6 +77,7 @@ func newPlayer(loc Coordinate) Player {
 		Location:      loc,
 		Threat:        0,
 		DataCollected: map[DataKind]float32{},
+		Effects:       activeEffects{},
 	}
 }
 
@@ -83,6 +89,23 @@ func (p *Player) tickThreat(rate float32) {
 	}
 }
 
+// hit starts the window after a bad bit hits the player, while any others pass through them.
+func (p *Player) hit(now time.Time, window time.Duration) {
+	p.invulnerableUntil = now.Add(window)
+}
+
+func (p *Player) isInvulnerable(now time.Time) bool {
+	return now.Before(p.invulnerableUntil)
+}
+
+// isFlashing says whether the player should be drawn lit up, so they blink while bad bits pass through them.
+func (p *Player) isFlashing(now time.Time, rate time.Duration) bool {
+	if rate <= 0 || !p.isInvulnerable(now) {
+		return false
+	}
+	return (p.invulnerableUntil.Sub(now)/rate)%2 == 0
+}
+
 func (p *Player) tickAction(t ActionType, rate float32) {
 	if p.CurrentAction.Type != t {
 		p.CurrentAction = playerAction{
@@ -107,7 +130,7 @@ func (p *Player) isDetected(maxThreat float32) bool {
 	return p.Threat >= maxThreat
 }
 
-func (p *Player) CollectLoot(loot Loot) {
+func (p *Player) CollectLoot(loot Loot, level *LevelConfig, now time.Time) {
 	if loot.Kind != LootEmpty {
 		p.Inventory = append(p.Inventory, loot)
 		sort.Slice(p.Inventory, func(i, j int) bool {
@@ -121,21 +144,35 @@ func (p *Player) CollectLoot(loot Loot) {
 				p.DataCollected[loot.DataKind] = loot.Data
 			}
 		case LootPowerUp:
-			// TODO
+			p.Effects.activate(loot.PowerUpKind, loot.Rarity, level.PowerUpDurationFor(loot.PowerUpKind, loot.Rarity), now)
+		case LootItem:
+			if len(p.Items) < MaxItems {
+				p.Items = append(p.Items, Item{Kind: loot.ItemKind, Rarity: loot.Rarity})
+			}
 		}
 		p.CurrentAction.Progress = 0
 	}
 }
 
-func (p *Player) tickMove(width, height int, threat float32) (Coordinate, bool) {
+// useItem removes the item in the given slot from the player's items, if the deploy function uses it up.
+func (p *Player) useItem(slot int, deploy func(Item) bool) {
+	if slot < 0 || slot >= len(p.Items) {
+		return
+	}
+	if deploy(p.Items[slot]) {
+		p.Items = append(p.Items[:slot], p.Items[slot+1:]...)
+	}
+}
+
+func (p *Player) tickMove(width, height int, threat float32, blocked func(Coordinate) bool) (Coordinate, bool) {
 	if p.automove {
 		p.tickThreat(threat)
-		return p.move(width, height), true
+		return p.move(width, height, blocked), true
 	}
 	return p.Location, false
 }
 
-func (p *Player) move(width, height int) Coordinate {
+func (p *Player) move(width, height int, blocked func(Coordinate) bool) Coordinate {
 	c := p.Location
 	switch p.direction {
 	case MoveUp:
@@ -155,15 +192,17 @@ func (p *Player) move(width, height int) Coordinate {
 			c.X++
 		}
 	}
+	if blocked(c) {
+		return p.Location
+	}
 	p.Location = c
 	return c
 }
 
-func (p *Player) HandleMoveInput(dir Direction, width, height int) Coordinate {
-	now := time.Now()
+func (p *Player) HandleMoveInput(now time.Time, dir Direction, width, height int, blocked func(Coordinate) bool) Coordinate {
 	elapsed := now.Sub(p.lastInput)
 	p.lastInput = now
 	p.automove = elapsed < 100*time.Millisecond && dir == p.direction
 	p.direction = dir
-	return p.move(width, height)
+	return p.move(width, height, blocked)
 }
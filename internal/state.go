@@ -3,6 +3,7 @@ package zen_doctor
 import (
 	"math/rand"
 	"sync"
+	"time"
 )
 
 type GameState struct {
@@ -13,24 +14,42 @@ type GameState struct {
 	view     View
 	mu       sync.Mutex
 	complete bool
+	alarm    bool // once raised, hunters are after the player
+	caught   bool
+	failed   LoseCondition // the lose condition that ended the level, if any
+	started  time.Time     // when the player entered the level
+
+	// everything random in the game comes from rng, and the time only ever comes from clock - so the same seed, and
+	// the same inputs at the same times, always play out the same way.
+	rng   *rand.Rand
+	clock Clock
 }
 
-func NewGameState(level Level, mode CompatibilityMode) GameState {
-	l := GetLevel(level)
+// NewGameState starts a level with the player somewhere at random.
+func NewGameState(l LevelConfig, mode CompatibilityMode, rng *rand.Rand, clock Clock) GameState {
 	return NewGameStateWithPlayerAt(Coordinate{
-		X: 1 + rand.Intn(l.Width-2),
-		Y: 1 + rand.Intn(l.Height-2),
-	}, level, mode)
+		X: 1 + rng.Intn(l.Width-2),
+		Y: 1 + rng.Intn(l.Height-2),
+	}, l, mode, rng, clock)
 }
 
-func NewGameStateWithPlayerAt(c Coordinate, level Level, mode CompatibilityMode) GameState {
-	l := GetLevel(level)
+func NewGameStateWithPlayerAt(c Coordinate, l LevelConfig, mode CompatibilityMode, rng *rand.Rand, clock Clock) GameState {
+	world := newWorld(&l, rng)
+	start, ok := l.Layout.spawnNear(c)
+	if !ok {
+		start = world.nearestOpenCell(c)
+	}
+	bits := newBitStream(&l, world.Walls, rng, clock)
+	bits.startSweep(start)
 	return GameState{
-		level:  &l,
-		bits:   newBitStream(&l),
-		player: newPlayer(c),
-		view:   newView(l.Width, l.Height, mode),
-		world:  newWorld(&l),
+		level:   &l,
+		bits:    bits,
+		player:  newPlayer(start),
+		view:    newView(l.Width, l.Height, mode),
+		world:   world,
+		started: clock.Now(),
+		rng:     rng,
+		clock:   clock,
 	}
 }
 
@@ -74,17 +93,45 @@ func (s *GameState) TickWorld() {
 
 	// check if world exit is unlocked
 	if s.isExitUnlocked() {
-		s.world.UnlockExit()
+		s.world.UnlockExit(s.player.Location)
 	}
 
 	s.world.TickFootprints()
+
+	// once the player is detected, the hunters are unleashed
+	if !s.alarm && s.player.isDetected(s.level.MaxThreat) {
+		s.raiseAlarm()
+	}
+	for _, enemy := range s.world.Enemies {
+		enemy.Tick(s)
+	}
+
+	// the level might have other ways to lose
+	if s.failed == nil {
+		for _, cond := range s.level.LoseConditions {
+			if cond.IsFailed(s) {
+				s.failed = cond
+				break
+			}
+		}
+	}
+}
+
+func (s *GameState) raiseAlarm() {
+	s.alarm = true
+	if s.level.HunterCount == 0 {
+		// nobody to hunt the player down, so they're caught right away
+		s.caught = true
+		return
+	}
+	s.world.spawnHunters(s.player.Location)
 }
 
 func (s *GameState) TickAnimations() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.view.TickAnimations()
+	s.view.TickAnimations(s.rng)
 	s.bits.TickAnimations()
 }
 
@@ -92,16 +139,22 @@ func (s *GameState) TickBitStream() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	s.bits.generator.Next(s.rng)
 	s.level.Updater.Tick(&s.bits)
+	s.tickObjects()
 	s.tickCollisions()
+	s.bits.startSweep(s.player.Location)
 }
 
 func (s *GameState) TickPlayer() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// handle player actions
-	if s.world.DidCollideWithExit(s.player.Location) {
+	// expire any power ups that have run out
+	s.player.Effects.tick(s.clock.Now())
+
+	// handle player actions - the exit only lets the player leave while the win conditions are still met
+	if s.world.DidCollideWithExit(s.player.Location) && s.isExitUnlocked() {
 		s.player.encounter(ActionTypeExit, s.player.Location)
 		s.player.tickAction(ActionTypeExit, s.level.LeaveSpeed)
 
@@ -110,13 +163,13 @@ func (s *GameState) TickPlayer() {
 		}
 	} else if s.world.DidCollideWithLoot(s.player.Location) {
 		s.player.encounter(ActionTypeLoot, s.player.Location)
-		s.player.tickAction(ActionTypeLoot, s.level.LootSpeed)
+		s.player.tickAction(ActionTypeLoot, s.lootSpeed())
 		// prevent loot from despawning while we loot it
 		s.world.TickLootAt(s.player.Location, -2*s.level.DataDecayRate)
 
 		// move loot to inventory once it's completely looted.
 		if s.player.CurrentAction.IsComplete() {
-			s.player.CollectLoot(s.world.ExtractLoot(s.player.Location))
+			s.player.CollectLoot(s.world.ExtractLoot(s.player.Location), s.level, s.clock.Now())
 		}
 	} else {
 		switch s.player.CurrentAction.Type {
@@ -127,27 +180,132 @@ func (s *GameState) TickPlayer() {
 		}
 
 		// only decay threat while not performing an action
-		s.player.tickThreat(s.level.ThreatDecay)
+		s.player.tickThreat(s.threatDecay())
+	}
+}
+
+func (s *GameState) tickObjects() {
+	remaining := make([]WorldObject, 0, len(s.world.Objects))
+	for _, obj := range s.world.Objects {
+		if obj.Tick(s) {
+			remaining = append(remaining, obj)
+		}
 	}
+	s.world.Objects = remaining
 }
 
-// check for collisions with bad bits
-func (s *GameState) tickCollisions() {
+// enter lets any objects at c react to the player moving onto it.
+func (s *GameState) enter(c Coordinate) {
+	for _, obj := range s.world.Objects {
+		obj.Enter(s, c)
+		if !s.player.Location.Equals(c) {
+			// the player was moved somewhere else
+			s.world.Visited(s.player.Location)
+			s.tickCollisions()
+			return
+		}
+	}
+}
+
+// tickCollisions applies every bit the player has run into since it was last called, and returns them - when the bit
+// stream moves fast, there can be more than one.
+func (s *GameState) tickCollisions() []collision {
 	// note: not wrapped in mutex since this is called from mutex protected calls already.
-	if threat, ok := s.bits.DidCollideWithBit(s.level, s.player.Location, RevealedBitHelpful); ok {
-		// good stream
-		s.player.tickThreat(-1 * threat)
-		s.bits.NeutralizeBit(s.player.Location)
+	s.bits.watch(s.player.Location)
+	collisions := s.bits.collisions()
+	now := s.clock.Now()
+	for _, hit := range collisions {
+		threat := hit.bits.Threat(s.level)
+		switch {
+		case hit.bits.Revealed == RevealedBitHelpful:
+			// good stream
+			s.player.tickThreat(-1 * threat)
+			s.bits.neutralize(hit.bits.id)
+		case s.player.Effects.IsActive(PowerUpBadBitsAreGood):
+			// bad stream is treated like a good stream
+			s.player.tickThreat(-1 * threat)
+			s.bits.neutralize(hit.bits.id)
+		case s.player.Effects.IsActive(PowerUpBadBitImmunity):
+			// bad stream passes right through
+		case s.player.isInvulnerable(now):
+			// the player was just hit, so the bad stream passes right through for a moment
+		default:
+			// bad stream - each bit only hits once
+			s.player.tickThreat(threat)
+			s.player.hit(now, s.level.HitInvulnerability)
+			s.bits.spend(hit.bits.id)
+		}
+	}
+	return collisions
+}
+
+// the following take any active power ups into account when reading values from the level config.
+func (s *GameState) viewDist() float64 {
+	if s.player.Effects.IsActive(PowerUpVisionRange) {
+		return s.level.ViewDist + s.level.PowerUpViewDist
+	}
+	return s.level.ViewDist
+}
+
+func (s *GameState) threatDecay() float32 {
+	if s.player.Effects.IsActive(PowerUpThreatDecay) {
+		return s.level.ThreatDecay * s.level.PowerUpThreatDecay
 	}
+	return s.level.ThreatDecay
+}
 
-	if threat, ok := s.bits.DidCollideWithBit(s.level, s.player.Location, RevealedBitHarmful); ok {
-		// bad stream
-		s.player.tickThreat(threat)
+func (s *GameState) lootSpeed() float32 {
+	if s.player.Effects.IsActive(PowerUpLootSpeed) {
+		return s.level.LootSpeed * s.level.PowerUpLootSpeed
 	}
+	return s.level.LootSpeed
+}
+
+func (s *GameState) timeInLevel() time.Duration {
+	return s.clock.Now().Sub(s.started)
+}
+
+func (s *GameState) threatPercent() float32 {
+	return s.player.Threat / s.level.MaxThreat * 100
 }
 
 func (s *GameState) IsGameOver() bool {
-	return s.player.isDetected(s.level.MaxThreat)
+	return s.caught || s.failed != nil
+}
+
+// IsFailed returns true if one of the level's lose conditions ended the game.
+func (s *GameState) IsFailed() bool {
+	return s.failed != nil
+}
+
+// LoseReason explains why the game is over, or returns an empty string if it isn't.
+func (s *GameState) LoseReason() string {
+	switch {
+	case s.failed != nil:
+		return s.failed.Describe(s.view.Mode)
+	case s.caught:
+		return "You were caught!"
+	}
+	return ""
+}
+
+// TimeLeft returns how long the player has until the level's time limit runs out, if it has one.
+func (s *GameState) TimeLeft() (time.Duration, bool) {
+	var left time.Duration
+	found := false
+	for _, cond := range s.level.LoseConditions {
+		if limit, ok := cond.(TimeLimit); ok && (!found || limit.left(s) < left) {
+			left, found = limit.left(s), true
+		}
+	}
+	if left < 0 {
+		left = 0
+	}
+	return left, found
+}
+
+func (s *GameState) IsAlarmRaised() bool {
+	return s.alarm
 }
 
 func (s *GameState) IsComplete() bool {
@@ -167,30 +325,65 @@ func (s *GameState) Inventory() []Loot {
 }
 
 func (s *GameState) DataWanted() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.view.DataWanted(s)
 }
 
 func (s *GameState) DataCollected() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.view.DataCollected(s)
 }
 
+func (s *GameState) ActivePowerUps() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.view.ActivePowerUps(s.player.Effects.Sorted(), s.clock.Now())
+}
+
 func (s *GameState) MovePlayer(dir Direction) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	c := s.player.HandleMoveInput(dir, s.level.Width, s.level.Height)
+	from := s.player.Location
+	c := s.player.HandleMoveInput(s.clock.Now(), dir, s.level.Width, s.level.Height, s.world.IsWall)
 	s.player.tickThreat(s.level.MovementThreat)
 	s.tickCollisions()
 	s.world.Visited(c)
+	if !c.Equals(from) {
+		s.enter(c)
+	}
 }
 
 func (s *GameState) TickMovement() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	c, applyDmg := s.player.tickMove(s.level.Width, s.level.Height, s.level.MovementThreat)
+	from := s.player.Location
+	c, applyDmg := s.player.tickMove(s.level.Width, s.level.Height, s.level.MovementThreat, s.world.IsWall)
 	if applyDmg {
 		s.tickCollisions()
 	}
 	s.world.Visited(c)
+	if !c.Equals(from) {
+		s.enter(c)
+	}
+}
+
+func (s *GameState) UseItem(slot int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.player.useItem(slot, func(item Item) bool {
+		return s.world.deploy(item, s.player.Location)
+	})
+}
+
+func (s *GameState) Hotbar() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.view.Hotbar(s.player.Items)
 }
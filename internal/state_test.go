@@ -0,0 +1,55 @@
+package zen_doctor
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testRand makes tests that need random numbers come out the same every time.
+func testRand() *rand.Rand {
+	return rand.New(rand.NewSource(1))
+}
+
+func TestSameSeedPlaysTheSameGame(t *testing.T) {
+	play := func(seed int64) (*GameState, []Coordinate) {
+		level := GetLevel(Level3)
+		clock := NewManualClock(time.Unix(0, 0))
+		state := NewGameState(level, CompatibilityAscii, rand.New(rand.NewSource(seed)), clock)
+		s := &state
+		var enemies []Coordinate
+		moves := []Direction{MoveUp, MoveLeft, MoveLeft, MoveDown, MoveRight}
+		for i := 0; i < 300; i++ {
+			clock.Advance(time.Second / 30)
+			if i%10 == 0 {
+				s.MovePlayer(moves[i/10%len(moves)])
+			}
+			s.TickWorld()
+			s.TickPlayer()
+			if i%5 == 0 {
+				s.TickBitStream()
+			}
+			s.TickAnimations()
+			for _, enemy := range s.world.Enemies {
+				enemies = append(enemies, enemy.Location())
+			}
+		}
+		return s, enemies
+	}
+
+	a, aEnemies := play(7)
+	b, bEnemies := play(7)
+	assert.Equal(t, a.player.Location, b.player.Location)
+	assert.Equal(t, a.player.Threat, b.player.Threat)
+	assert.Equal(t, a.world.Loot, b.world.Loot)
+	assert.Equal(t, aEnemies, bEnemies)
+	for i := range a.bits.grid.cells {
+		assert.Equal(t, a.bits.grid.cells[i].Revealed, b.bits.grid.cells[i].Revealed)
+		assert.Equal(t, a.bits.grid.cells[i].Value, b.bits.grid.cells[i].Value)
+	}
+
+	c, _ := play(8)
+	assert.NotEqual(t, a.world.Loot, c.world.Loot, "a different seed plays a different game")
+}
@@ -12,7 +12,7 @@ const (
 
 type AnimatedSymbol interface {
 	Symbol
-	Tick()
+	Tick(rng *rand.Rand)
 }
 
 type NoisySymbol struct {
@@ -23,11 +23,11 @@ type NoisySymbol struct {
 	useNoise bool
 }
 
-func (s *NoisySymbol) Tick() {
-	if rand.Float32() < s.Chance {
+func (s *NoisySymbol) Tick(rng *rand.Rand) {
+	if rng.Float32() < s.Chance {
 		// select noise
 		s.useNoise = true
-		s.selected = rand.Intn(len(s.Noise))
+		s.selected = rng.Intn(len(s.Noise))
 	} else {
 		s.useNoise = false
 	}
@@ -46,7 +46,7 @@ type LoopingSymbol struct {
 	Current int
 }
 
-func (s *LoopingSymbol) Tick() {
+func (s *LoopingSymbol) Tick(_ *rand.Rand) {
 	s.Current++
 	if s.Current >= len(s.Frames) {
 		s.Current = 0
@@ -328,6 +328,51 @@ var LootSpeedSymbol = symbol{
 	ASCII: `5`,
 }
 
+var WallSymbol = symbol{
+	Runic: `▓`,
+	Latin: `▓`,
+	ASCII: `#`,
+}
+
+// Item symbols
+var PylonSymbol = symbol{
+	Runic: `ᛦ`,
+	Latin: `±`,
+	ASCII: `+`,
+}
+
+var PortalSymbol = symbol{
+	Runic: `◎`,
+	Latin: `§`,
+	ASCII: `%`,
+}
+
+// Enemy symbols
+var PatrollerSymbol = symbol{
+	Runic: `ᛗ`,
+	Latin: `M`,
+	ASCII: `M`,
+}
+
+var TrackerSymbol = symbol{
+	Runic: `ᛞ`,
+	Latin: `T`,
+	ASCII: `T`,
+}
+
+var HunterSymbol = symbol{
+	Runic: `ᛢ`,
+	Latin: `H`,
+	ASCII: `H`,
+}
+
+// Level editor symbols
+var EmitterSymbol = symbol{
+	Runic: `✺`,
+	Latin: `¤`,
+	ASCII: `*`,
+}
+
 const (
 	QuestionSymbol  = `?`
 	FootprintSymbol = `.`
@@ -0,0 +1,134 @@
+package zen_doctor
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// loot table chances are floats, so allow a little rounding error when adding them up.
+const chanceTolerance = 1e-6
+
+// minLevelSize is the smallest width or height a level can have.
+const minLevelSize = 3
+
+// Validate checks the rules that every level must follow, and returns everything that is wrong with it.
+func (l LevelConfig) Validate() []error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, errors.Errorf(format, args...))
+		}
+	}
+
+	// each level must have an updater and win conditions defined
+	check(l.Updater != nil, "must have updater")
+	check(len(l.WinConditions) > 0, "must have win conditions")
+	check(len(l.PowerUpLootTable) > 0, "must have power ups")
+	check(len(l.DataLootTable) > 0, "must have data")
+	check(len(l.ItemLootTable) > 0, "must have items")
+
+	// the sum of the loot tables must be 1, and each chance must be between 0 and 1
+	tables := []struct {
+		name  string
+		table lootTable
+	}{
+		{"DataLootTable", l.DataLootTable},
+		{"PowerUpLootTable", l.PowerUpLootTable},
+		{"ItemLootTable", l.ItemLootTable},
+	}
+	for _, t := range tables {
+		sum := float32(0.0)
+		for i, entry := range t.table {
+			check(0 <= entry.Chance && entry.Chance <= 1, "chance for %s[%d] must be between 0 and 1, got %v", t.name, i, entry.Chance)
+			sum += entry.Chance
+		}
+		if len(t.table) > 0 {
+			check(math.Abs(float64(sum)-1) < chanceTolerance, "sum of chances in %s must equal 1, got %v", t.name, sum)
+		}
+	}
+
+	// loot exists in the loot table for all win conditions
+	for _, kind := range wantsDataAll(l.WinConditions) {
+		found := false
+		for _, loot := range l.DataLootTable {
+			if kind == loot.Data {
+				found = true
+			}
+		}
+		check(found, "must include loot table for all win conditions, missing %v", kind)
+	}
+
+	// all decay values should be negative
+	check(0 > l.LeaveSpeedDecay, "LeaveSpeedDecay must be negative")
+	check(0 > l.DataDecayRate, "DataDecayRate must be negative")
+	check(0 > l.FootprintDecay, "FootprintDecay must be negative")
+	check(0 > l.ThreatDecay, "ThreatDecay must be negative")
+	check(0 > l.LootSpeedDecay, "LootSpeedDecay must be negative")
+	check(0 > l.PowerUpDecayRate, "PowerUpDecayRate must be negative")
+	check(0 > l.ItemDecayRate, "ItemDecayRate must be negative")
+
+	// the player starts inside the border, which needs room on every side, and random patrol routes need room too
+	check(l.Width >= minLevelSize, "Width must be at least %d", minLevelSize)
+	check(l.Height >= minLevelSize, "Height must be at least %d", minLevelSize)
+	if l.EnemyCount > len(l.PatrolRoutes) {
+		check(l.Width >= minPatrolSize && l.Height >= minPatrolSize, "EnemyCount without PatrolRoutes needs a level at least %dx%d",
+			minPatrolSize, minPatrolSize)
+	}
+
+	// data multipliers should be non-zero
+	for kind, multiplier := range l.DataMultipliers {
+		check(multiplier > 0, "Data multiplier for %v must be positive", kind)
+	}
+
+	// bit stream chances must be between 0 and 1
+	check(0 <= l.BitStreamChance && l.BitStreamChance <= 1, "BitStreamChance must be between 0 and 1")
+	check(0 <= l.BadBitChance && l.BadBitChance <= 1, "BadBitChance must be between 0 and 1")
+	check(0 <= l.GoodBitChance && l.GoodBitChance <= 1, "GoodBitChance must be between 0 and 1")
+	check(l.HitInvulnerability >= 0, "HitInvulnerability can't be negative")
+	check(l.HitFlashRate >= 0, "HitFlashRate can't be negative")
+
+	// things the game loop would choke on
+	check(l.FPS > 0, "FPS must be positive")
+	check(l.MaxThreat > 0, "MaxThreat must be positive")
+	check(l.EnemyCount >= 0, "EnemyCount can't be negative")
+	check(l.TrackerCount >= 0, "TrackerCount can't be negative")
+	check(l.HunterCount >= 0, "HunterCount can't be negative")
+	for i, route := range l.PatrolRoutes {
+		check(len(route) > 0, "PatrolRoutes[%d] must have at least one waypoint", i)
+	}
+	if exit, ok := l.ExitPlacement.(ExitAt); ok {
+		check(0 <= exit.X && exit.X < l.Width && 0 <= exit.Y && exit.Y < l.Height, "ExitPlacement at %d,%d is outside the level", exit.X, exit.Y)
+	}
+
+	// there has to be somewhere to put the player, and everything else
+	if l.Width >= minLevelSize && l.Height >= minLevelSize {
+		check(l.hasRoom(), "walls must leave an open cell the player can get to")
+	}
+
+	// hand-drawn maps decide how big the level is
+	if l.Layout != nil {
+		check(l.Width == l.Layout.Width && l.Height == l.Layout.Height, "Layout is %dx%d, but the level is %dx%d",
+			l.Layout.Width, l.Layout.Height, l.Width, l.Height)
+	}
+	return errs
+}
+
+// hasRoom returns true if the level's walls leave at least one cell open where the player can go - they stop one short
+// of the right and bottom edges. Mazes always leave room, so only the walls the level lists count.
+func (l LevelConfig) hasRoom() bool {
+	walls := make(map[Coordinate]bool)
+	for _, wall := range l.Walls {
+		for _, c := range wall.Coordinates() {
+			walls[c] = true
+		}
+	}
+	for y := 0; y < l.Height-1; y++ {
+		for x := 0; x < l.Width-1; x++ {
+			if !walls[Coordinate{x, y}] {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -2,6 +2,7 @@ package zen_doctor
 
 import (
 	"fmt"
+	"math/rand"
 	"strings"
 	"time"
 )
@@ -19,23 +20,26 @@ type Color int
 const (
 	Blue        Color = 20
 	LightBlue   Color = 81
+	DarkTeal    Color = 23
 	Teal        Color = 85
 	Green       Color = 118
 	Purple      Color = 129
 	Brown       Color = 130
 	Lavender    Color = 147
 	Red         Color = 1
+	DarkRed     Color = 52
 	YellowGreen Color = 190
 	Pink        Color = 200
 	Orange      Color = 208
 	Yellow      Color = 226
 	Black       Color = 232
 	DarkGray    Color = 235
+	Gray        Color = 240
 	LightGray   Color = 245
 	White       Color = 255
 )
 
-func GameOver(didWin bool, elapsed time.Duration, mode CompatibilityMode, collection ...Loot) string {
+func GameOver(didWin bool, reason string, elapsed time.Duration, mode CompatibilityMode, collection ...Loot) string {
 
 	// group collection by symbol and then by rarity. We want a display like:
 	// 1Δ 5Δ 13Δ 21Δ 6Δ
@@ -75,7 +79,7 @@ func GameOver(didWin bool, elapsed time.Duration, mode CompatibilityMode, collec
 	if didWin {
 		b.WriteString(WithColor(Green, "You did it! Results:\n"))
 	} else {
-		b.WriteString(WithColor(Red, "You were caught! Results:\n"))
+		b.WriteString(WithColor(Red, reason+" Results:\n"))
 	}
 	hierarchy := []DataKind{DataKindDelta, DataKindLambda, DataKindSigma, DataKindOmega}
 	for _, dk := range hierarchy {
@@ -169,7 +173,7 @@ func (v *View) Apply(s *GameState) {
 			c := Coordinate{x, y}
 
 			// Hidden bit stream is always shown if there's nothing else.
-			bs := s.bits.stream[c]
+			bs := s.bits.At(c)
 			cell := Cell{
 				Background: Black,
 				Foreground: DarkGray,
@@ -181,8 +185,13 @@ func (v *View) Apply(s *GameState) {
 				cell.Foreground, cell.Symbol = footprint.WithIntensity()
 			}
 
+			// walls
+			if s.world.IsWall(c) {
+				cell.Foreground, cell.Symbol = Gray, WallSymbol.ForMode(v.Mode)
+			}
+
 			// determine if the bit stream and loot should be revealed, by checking the distance from this tile to the player
-			inPlayerRange := s.player.Location.InRange(s.level.ViewDist, c)
+			inPlayerRange := s.player.Location.InRange(s.viewDist(), c)
 
 			// revealed bit stream
 			if inPlayerRange {
@@ -203,6 +212,16 @@ func (v *View) Apply(s *GameState) {
 				}
 			}
 
+			// objects the player has placed
+			for _, obj := range s.world.Objects {
+				obj.Render(c, &cell, v.Mode)
+			}
+
+			// enemies and their vision
+			for _, enemy := range s.world.Enemies {
+				enemy.Render(c, &cell, v.Mode)
+			}
+
 			// Exit location
 			if s.world.Exit != nil && c.Equals(*s.world.Exit) {
 				cell.Foreground, cell.Symbol = v.exitSymbol()
@@ -211,6 +230,9 @@ func (v *View) Apply(s *GameState) {
 			// finally, player location
 			if c.Equals(s.player.Location) {
 				cell.Foreground, cell.Symbol = YellowGreen, PlayerSymbol.ForMode(v.Mode)
+				if s.player.isFlashing(s.clock.Now(), s.level.HitFlashRate) {
+					cell.Background, cell.Foreground = Red, White
+				}
 			}
 
 			v.Data[c] = cell
@@ -224,6 +246,9 @@ func (v *View) ThreatMeter(current, max float32) string {
 	b := strings.Builder{}
 
 	// find the percent, convert that to an int over v.Width
+	if current > max {
+		current = max
+	}
 	percent := current / max
 	threat := int(percent * float32(v.Width))
 
@@ -258,7 +283,7 @@ func (v *View) ActionProgressMeter(current, max float32) string {
 func (v *View) DataWanted(state *GameState) string {
 	b := strings.Builder{}
 	for _, want := range state.Level().WinConditions {
-		b.WriteString(fmt.Sprintf("%s %.0f\n", want.Kind.ForMode(v.Mode), want.Amount))
+		b.WriteString(want.Describe(v.Mode) + "\n")
 	}
 	return b.String()
 }
@@ -266,12 +291,8 @@ func (v *View) DataWanted(state *GameState) string {
 func (v *View) DataCollected(state *GameState) string {
 	b := strings.Builder{}
 	for _, want := range state.level.WinConditions {
-		if amount, ok := state.player.DataCollected[want.Kind]; ok {
-			str := fmt.Sprintf("%s %.0f\n", want.Kind.ForMode(v.Mode), amount)
-			if amount > want.Amount {
-				str = WithColor(Green, str)
-			}
-			b.WriteString(str)
+		if progress := conditionProgress(want, state, v.Mode); progress != "" {
+			b.WriteString(progress + "\n")
 		}
 	}
 	if state.isExitUnlocked() {
@@ -280,8 +301,27 @@ func (v *View) DataCollected(state *GameState) string {
 	return b.String()
 }
 
-func (v *View) TickAnimations() {
-	v.ExitSymbol.Tick()
+// Hotbar lists the items the player is carrying, next to the number key that uses them.
+func (v *View) Hotbar(items []Item) string {
+	b := strings.Builder{}
+	for i, item := range items {
+		b.WriteString(fmt.Sprintf("%d%s ", i+1, WithColor(item.Rarity.Color(), item.Kind.ForMode(v.Mode))))
+	}
+	return b.String()
+}
+
+// ActivePowerUps lists the power ups affecting the player, and how long each one has left.
+func (v *View) ActivePowerUps(effects []powerUpEffect, now time.Time) string {
+	b := strings.Builder{}
+	for _, effect := range effects {
+		symbol := WithColor(effect.Rarity.Color(), effect.Kind.ForMode(v.Mode))
+		b.WriteString(fmt.Sprintf("%s %-9s %s\n", symbol, effect.Kind, ElapsedTime(effect.Remaining(now))))
+	}
+	return b.String()
+}
+
+func (v *View) TickAnimations(rng *rand.Rand) {
+	v.ExitSymbol.Tick(rng)
 }
 
 func (v *View) exitSymbol() (Color, string) {
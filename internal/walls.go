@@ -0,0 +1,168 @@
+package zen_doctor
+
+import "math/rand"
+
+// Wall is a rectangle of wall tiles. Nothing can pass through walls - not the player, enemies, or the bit stream.
+type Wall struct {
+	X      int
+	Y      int
+	Width  int
+	Height int
+}
+
+func (w Wall) Coordinates() []Coordinate {
+	coords := make([]Coordinate, 0, w.Width*w.Height)
+	for x := w.X; x < w.X+w.Width; x++ {
+		for y := w.Y; y < w.Y+w.Height; y++ {
+			coords = append(coords, Coordinate{x, y})
+		}
+	}
+	return coords
+}
+
+func newWalls(level *LevelConfig, rng *rand.Rand) map[Coordinate]bool {
+	walls := make(map[Coordinate]bool)
+	if level.Kind == LevelKindMainframe {
+		seed := level.MazeSeed
+		if seed == 0 {
+			seed = rng.Int63()
+		}
+		// mainframe levels are built on top of a maze
+		walls = newMaze(level.Width, level.Height, seed)
+	}
+	for _, wall := range level.Walls {
+		for _, c := range wall.Coordinates() {
+			walls[c] = true
+		}
+	}
+	return walls
+}
+
+func (w *World) IsWall(c Coordinate) bool {
+	return w.Walls[c]
+}
+
+// isOpen returns true if c is inside the map and not a wall.
+func (w *World) isOpen(c Coordinate) bool {
+	return c.X >= 0 && c.X < w.Level.Width && c.Y >= 0 && c.Y < w.Level.Height && !w.IsWall(c)
+}
+
+// randomOpenCell picks an open cell at random, somewhere the player could get to.
+func (w *World) randomOpenCell() Coordinate {
+	for attempt := 0; attempt < 100; attempt++ {
+		c := Coordinate{w.rng.Intn(w.Level.Width - 1), w.rng.Intn(w.Level.Height - 1)}
+		if !w.IsWall(c) {
+			return c
+		}
+	}
+	// the level is nearly all wall, so look through it in order instead
+	for y := 0; y < w.Level.Height-1; y++ {
+		for x := 0; x < w.Level.Width-1; x++ {
+			if c := (Coordinate{x, y}); !w.IsWall(c) {
+				return c
+			}
+		}
+	}
+	// there's no room at all, which Validate doesn't let through
+	return Coordinate{}
+}
+
+// nearestOpenCell returns c if it's open, otherwise the closest cell to it that is.
+func (w *World) nearestOpenCell(c Coordinate) Coordinate {
+	if w.isOpen(c) {
+		return c
+	}
+	visited := map[Coordinate]bool{c: true}
+	queue := []Coordinate{c}
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		for d := MoveUp; d <= MoveRight; d++ {
+			dx, dy := d.Delta()
+			n := Coordinate{next.X + dx, next.Y + dy}
+			if visited[n] || n.X < 0 || n.X >= w.Level.Width || n.Y < 0 || n.Y >= w.Level.Height {
+				continue
+			}
+			if !w.IsWall(n) {
+				return n
+			}
+			visited[n] = true
+			queue = append(queue, n)
+		}
+	}
+	return w.randomOpenCell()
+}
+
+// neighbor returns the cell one step away from c in the given direction, if it can be moved to.
+// Diagonal steps can't cut the corner of a wall.
+func (w *World) neighbor(c Coordinate, dir Direction) (Coordinate, bool) {
+	dx, dy := dir.Delta()
+	n := Coordinate{c.X + dx, c.Y + dy}
+	if !w.isOpen(n) {
+		return n, false
+	}
+	if dx != 0 && dy != 0 && (w.IsWall(Coordinate{c.X + dx, c.Y}) || w.IsWall(Coordinate{c.X, c.Y + dy})) {
+		return n, false
+	}
+	return n, true
+}
+
+// stepToward returns the next step along the shortest path between two cells, going around any walls.
+// It returns false if there is no way to get there.
+func (w *World) stepToward(from, to Coordinate) (Coordinate, Direction, bool) {
+	if from.Equals(to) || !w.isOpen(to) {
+		return from, MoveUp, false
+	}
+	// search backwards from the destination, so the first time we find our starting point we know which way to go.
+	visited := make([]bool, w.Level.Width*w.Level.Height)
+	visited[to.Y*w.Level.Width+to.X] = true
+	queue := []Coordinate{to}
+	for len(queue) > 0 {
+		c := queue[0]
+		queue = queue[1:]
+		for d := MoveUp; d <= MoveDownRight; d++ {
+			n, ok := w.neighbor(c, d)
+			if !ok || visited[n.Y*w.Level.Width+n.X] {
+				continue
+			}
+			if n.Equals(from) {
+				dir, _ := directionOf(c.X-from.X, c.Y-from.Y)
+				return c, dir, true
+			}
+			visited[n.Y*w.Level.Width+n.X] = true
+			queue = append(queue, n)
+		}
+	}
+	return from, MoveUp, false
+}
+
+// lineOfSight returns true if there are no walls between the two cells.
+func (w *World) lineOfSight(from, to Coordinate) bool {
+	// Bresenham's line algorithm
+	dx, dy := abs(to.X-from.X), -abs(to.Y-from.Y)
+	sx, sy := sign(to.X-from.X), sign(to.Y-from.Y)
+	err := dx + dy
+	c := from
+	for !c.Equals(to) {
+		if !c.Equals(from) && w.IsWall(c) {
+			return false
+		}
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			c.X += sx
+		}
+		if e2 <= dx {
+			err += dx
+			c.Y += sy
+		}
+	}
+	return true
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
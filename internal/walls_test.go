@@ -0,0 +1,157 @@
+package zen_doctor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// layoutLevel builds an empty level with the walls from a drawing.
+func layoutLevel(t *testing.T, drawing string) LevelConfig {
+	layout, err := ParseLayout([]byte(drawing[1:]))
+	require.NoError(t, err)
+	level := GetLevel(Tutorial)
+	level.Walls = nil
+	level.InitialData, level.InitialPowerUps, level.InitialItems = 0, 0, 0
+	level.EnemyCount, level.TrackerCount, level.HunterCount = 0, 0, 0
+	layout.apply(&level)
+	return level
+}
+
+func layoutWorld(t *testing.T, drawing string) World {
+	level := layoutLevel(t, drawing)
+	return newWorld(&level, testRand())
+}
+
+func TestNeighbor(t *testing.T) {
+	w := layoutWorld(t, `
+.....
+.#...
+.....
+`)
+	tests := map[string]struct {
+		from Coordinate
+		dir  Direction
+		want Coordinate
+		ok   bool
+	}{
+		"open":                   {Coordinate{0, 0}, MoveDown, Coordinate{0, 1}, true},
+		"into a wall":            {Coordinate{0, 1}, MoveRight, Coordinate{1, 1}, false},
+		"off the edge":           {Coordinate{0, 0}, MoveLeft, Coordinate{-1, 0}, false},
+		"diagonal":               {Coordinate{2, 1}, MoveUpRight, Coordinate{3, 0}, true},
+		"cutting a corner":       {Coordinate{1, 0}, MoveDownLeft, Coordinate{0, 1}, false},
+		"cutting the other side": {Coordinate{2, 1}, MoveDownLeft, Coordinate{1, 2}, false},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, ok := w.neighbor(tc.from, tc.dir)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.ok, ok)
+		})
+	}
+}
+
+func TestStepToward(t *testing.T) {
+	w := layoutWorld(t, `
+.......
+...#...
+...#...
+...#...
+.......
+...###.
+...#.#.
+...###.
+`)
+	tests := map[string]struct {
+		from, to Coordinate
+		steps    int // how many steps it takes to get there, or 0 if it can't be done
+	}{
+		"straight":      {Coordinate{0, 0}, Coordinate{6, 0}, 6},
+		"around a wall": {Coordinate{1, 2}, Coordinate{5, 2}, 6}, // it can't cut across the wall's corners
+		"already there": {Coordinate{1, 2}, Coordinate{1, 2}, 0},
+		"onto a wall":   {Coordinate{1, 2}, Coordinate{3, 2}, 0},
+		"walled in":     {Coordinate{1, 6}, Coordinate{4, 6}, 0},
+		"around a box":  {Coordinate{2, 6}, Coordinate{6, 4}, 6},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			c, steps := tc.from, 0
+			for ; steps < 100; steps++ {
+				next, dir, ok := w.stepToward(c, tc.to)
+				if !ok {
+					assert.Equal(t, c, next, "stays put when there's no way to go")
+					break
+				}
+				n, open := w.neighbor(c, dir)
+				require.True(t, open, "step from %v to %v isn't allowed", c, next)
+				require.Equal(t, n, next, "the direction goes to the step")
+				c = next
+			}
+			if tc.steps == 0 {
+				assert.Equal(t, tc.from, c)
+				assert.Zero(t, steps)
+			} else {
+				assert.Equal(t, tc.to, c)
+				assert.Equal(t, tc.steps, steps)
+			}
+		})
+	}
+}
+
+func TestLineOfSight(t *testing.T) {
+	w := layoutWorld(t, `
+.......
+...#...
+.......
+`)
+	tests := map[string]struct {
+		from, to Coordinate
+		want     bool
+	}{
+		"clear":             {Coordinate{0, 0}, Coordinate{6, 0}, true},
+		"through a wall":    {Coordinate{0, 1}, Coordinate{6, 1}, false},
+		"diagonal blocked":  {Coordinate{1, 0}, Coordinate{5, 2}, false},
+		"past the wall":     {Coordinate{0, 2}, Coordinate{6, 2}, true},
+		"next to the wall":  {Coordinate{2, 1}, Coordinate{2, 0}, true},
+		"looking at a wall": {Coordinate{0, 1}, Coordinate{3, 1}, true},
+		"from a wall":       {Coordinate{3, 1}, Coordinate{6, 1}, true},
+		"the same cell":     {Coordinate{4, 1}, Coordinate{4, 1}, true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, w.lineOfSight(tc.from, tc.to))
+			assert.Equal(t, tc.want, w.lineOfSight(tc.to, tc.from), "it works both ways")
+		})
+	}
+}
+
+func TestRandomOpenCell(t *testing.T) {
+	w := layoutWorld(t, `
+#####
+###.#
+#####
+`)
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, Coordinate{3, 1}, w.randomOpenCell(), "finds the only open cell")
+	}
+
+	w = layoutWorld(t, `
+####.
+####.
+.....
+`)
+	assert.NotPanics(t, func() { w.randomOpenCell() }, "gives up when there's nowhere the player can get to")
+}
+
+func TestNearestOpenCell(t *testing.T) {
+	w := layoutWorld(t, `
+.....
+.###.
+.....
+`)
+	assert.Equal(t, Coordinate{0, 0}, w.nearestOpenCell(Coordinate{0, 0}), "open cells stay where they are")
+	c := w.nearestOpenCell(Coordinate{2, 1})
+	assert.True(t, w.isOpen(c))
+	assert.Equal(t, 1, abs(c.X-2)+abs(c.Y-1), "moves to the closest open cell")
+}
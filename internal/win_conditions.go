@@ -0,0 +1,238 @@
+package zen_doctor
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// WinCondition is something the player has to do to unlock the exit. The exit only lets the player leave while all
+// of the level's conditions are met.
+type WinCondition interface {
+	IsMet(s *GameState) bool
+	// Describe says what the condition wants, for the panel showing what's needed.
+	Describe(mode CompatibilityMode) string
+	// Progress says how far the player has got, for the panel showing what's been done. It can be empty.
+	Progress(s *GameState, mode CompatibilityMode) string
+}
+
+// conditions that need data use this to say which kinds, so levels can make sure that data turns up.
+type dataWanter interface {
+	wantsData() []DataKind
+}
+
+// CollectData is met once the player has collected enough data of a kind.
+type CollectData struct {
+	Kind   DataKind
+	Amount float32
+}
+
+func (c CollectData) IsMet(s *GameState) bool {
+	if amount, ok := s.player.DataCollected[c.Kind]; ok {
+		return amount >= c.Amount
+	}
+	return false
+}
+
+func (c CollectData) Describe(mode CompatibilityMode) string {
+	return fmt.Sprintf("%s %.0f", c.Kind.ForMode(mode), c.Amount)
+}
+
+func (c CollectData) Progress(s *GameState, mode CompatibilityMode) string {
+	if amount, ok := s.player.DataCollected[c.Kind]; ok {
+		return fmt.Sprintf("%s %.0f", c.Kind.ForMode(mode), amount)
+	}
+	return ""
+}
+
+func (c CollectData) wantsData() []DataKind {
+	return []DataKind{c.Kind}
+}
+
+// SurviveFor is met once the player has been in the level long enough.
+type SurviveFor struct {
+	Duration time.Duration
+}
+
+func (c SurviveFor) IsMet(s *GameState) bool {
+	return s.timeInLevel() >= c.Duration
+}
+
+func (c SurviveFor) Describe(_ CompatibilityMode) string {
+	return fmt.Sprintf("Survive %s", ElapsedTime(c.Duration))
+}
+
+func (c SurviveFor) Progress(s *GameState, _ CompatibilityMode) string {
+	left := c.Duration - s.timeInLevel()
+	if left <= 0 {
+		return "Survived"
+	}
+	return fmt.Sprintf("%s to go", ElapsedTime(left))
+}
+
+// ThreatUnder is met while the player's threat is below a percentage of the max, so they have to leave quietly.
+type ThreatUnder struct {
+	Percent float32
+}
+
+func (c ThreatUnder) IsMet(s *GameState) bool {
+	return s.threatPercent() < c.Percent
+}
+
+func (c ThreatUnder) Describe(_ CompatibilityMode) string {
+	return fmt.Sprintf("Exit at <%.0f%% threat", c.Percent)
+}
+
+func (c ThreatUnder) Progress(s *GameState, _ CompatibilityMode) string {
+	return fmt.Sprintf("Threat %.0f%%", s.threatPercent())
+}
+
+// CollectRarity is met once the player has collected enough loot at least as rare as Rarity.
+type CollectRarity struct {
+	Rarity Rarity
+	Count  int
+}
+
+func (c CollectRarity) IsMet(s *GameState) bool {
+	return c.collected(s) >= c.Count
+}
+
+func (c CollectRarity) collected(s *GameState) int {
+	count := 0
+	for _, loot := range s.player.Inventory {
+		if loot.Rarity >= c.Rarity {
+			count++
+		}
+	}
+	return count
+}
+
+func (c CollectRarity) Describe(_ CompatibilityMode) string {
+	return fmt.Sprintf("%d %s+ loot", c.Count, WithColor(c.Rarity.Color(), c.Rarity.String()))
+}
+
+func (c CollectRarity) Progress(s *GameState, _ CompatibilityMode) string {
+	return fmt.Sprintf("%s+ %d/%d", WithColor(c.Rarity.Color(), c.Rarity.String()), c.collected(s), c.Count)
+}
+
+// CollectAll is met once the player has collected some data of a kind, and there's none of it left in the world.
+type CollectAll struct {
+	Kind DataKind
+}
+
+func (c CollectAll) IsMet(s *GameState) bool {
+	_, collected := s.player.DataCollected[c.Kind]
+	return collected && c.left(s) == 0
+}
+
+func (c CollectAll) left(s *GameState) int {
+	left := 0
+	for _, loot := range s.world.Loot {
+		if loot.Kind == LootData && loot.DataKind == c.Kind {
+			left++
+		}
+	}
+	return left
+}
+
+func (c CollectAll) Describe(mode CompatibilityMode) string {
+	return fmt.Sprintf("All %s", c.Kind.ForMode(mode))
+}
+
+func (c CollectAll) Progress(s *GameState, mode CompatibilityMode) string {
+	return fmt.Sprintf("%s %d left", c.Kind.ForMode(mode), c.left(s))
+}
+
+func (c CollectAll) wantsData() []DataKind {
+	return []DataKind{c.Kind}
+}
+
+// AllOf is met when every one of its conditions is.
+type AllOf []WinCondition
+
+func (c AllOf) IsMet(s *GameState) bool {
+	for _, cond := range c {
+		if !cond.IsMet(s) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c AllOf) Describe(mode CompatibilityMode) string {
+	return describeAll("All of:", c, mode)
+}
+
+func (c AllOf) Progress(s *GameState, mode CompatibilityMode) string {
+	return progressAll("All of:", c, s, mode)
+}
+
+func (c AllOf) wantsData() []DataKind {
+	return wantsDataAll(c)
+}
+
+// AnyOf is met when at least one of its conditions is.
+type AnyOf []WinCondition
+
+func (c AnyOf) IsMet(s *GameState) bool {
+	for _, cond := range c {
+		if cond.IsMet(s) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c AnyOf) Describe(mode CompatibilityMode) string {
+	return describeAll("Any of:", c, mode)
+}
+
+func (c AnyOf) Progress(s *GameState, mode CompatibilityMode) string {
+	return progressAll("Any of:", c, s, mode)
+}
+
+func (c AnyOf) wantsData() []DataKind {
+	return wantsDataAll(c)
+}
+
+// the combinators list their conditions under a heading, indented so it's clear which ones belong together.
+func describeAll(heading string, conditions []WinCondition, mode CompatibilityMode) string {
+	lines := []string{heading}
+	for _, cond := range conditions {
+		lines = append(lines, indent(cond.Describe(mode)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func progressAll(heading string, conditions []WinCondition, s *GameState, mode CompatibilityMode) string {
+	lines := []string{heading}
+	for _, cond := range conditions {
+		if progress := conditionProgress(cond, s, mode); progress != "" {
+			lines = append(lines, indent(progress))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// conditionProgress shows the progress on a condition, in green once it's met.
+func conditionProgress(cond WinCondition, s *GameState, mode CompatibilityMode) string {
+	progress := cond.Progress(s, mode)
+	if progress != "" && cond.IsMet(s) {
+		progress = WithColor(Green, progress)
+	}
+	return progress
+}
+
+func indent(text string) string {
+	return "  " + strings.ReplaceAll(text, "\n", "\n  ")
+}
+
+func wantsDataAll(conditions []WinCondition) []DataKind {
+	var kinds []DataKind
+	for _, cond := range conditions {
+		if wanter, ok := cond.(dataWanter); ok {
+			kinds = append(kinds, wanter.wantsData()...)
+		}
+	}
+	return kinds
+}
@@ -0,0 +1,85 @@
+package zen_doctor
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWinConditions(t *testing.T) {
+	s := NewGameState(GetLevel(Tutorial), CompatibilityAscii, testRand(), SystemClock)
+	s.world.Loot = map[Coordinate]Loot{{1, 1}: {Kind: LootData, DataKind: DataKindSigma}}
+	s.player.DataCollected[DataKindDelta] = 50
+	s.player.Inventory = []Loot{{Kind: LootData, Rarity: Rare}, {Kind: LootPowerUp, Rarity: Epic}, {Kind: LootData, Rarity: Junk}}
+	s.player.Threat = s.level.MaxThreat / 2
+	s.started = time.Now().Add(-30 * time.Second)
+
+	tests := map[string]struct {
+		cond     WinCondition
+		met      bool
+		progress string
+	}{
+		"data":              {CollectData{Kind: DataKindDelta, Amount: 40}, true, "W 50"},
+		"not enough data":   {CollectData{Kind: DataKindDelta, Amount: 100}, false, "W 50"},
+		"no data yet":       {CollectData{Kind: DataKindOmega, Amount: 1}, false, ""},
+		"survived":          {SurviveFor{Duration: 20 * time.Second}, true, "Survived"},
+		"still surviving":   {SurviveFor{Duration: time.Minute}, false, "30.0s to go"},
+		"quiet enough":      {ThreatUnder{Percent: 60}, true, "Threat 50%"},
+		"too loud":          {ThreatUnder{Percent: 40}, false, "Threat 50%"},
+		"rare loot":         {CollectRarity{Rarity: Rare, Count: 2}, true, "/2"},
+		"not rare enough":   {CollectRarity{Rarity: Epic, Count: 2}, false, "/2"},
+		"all collected":     {CollectAll{Kind: DataKindDelta}, true, "W 0 left"},
+		"some left":         {CollectAll{Kind: DataKindSigma}, false, "Y 1 left"},
+		"all of":            {AllOf{CollectData{Kind: DataKindDelta, Amount: 40}, ThreatUnder{Percent: 60}}, true, "All of:\n  "},
+		"not all of":        {AllOf{CollectData{Kind: DataKindDelta, Amount: 40}, ThreatUnder{Percent: 40}}, false, "\n  Threat 50%"},
+		"any of":            {AnyOf{CollectAll{Kind: DataKindSigma}, ThreatUnder{Percent: 60}}, true, "Any of:\n  Y 1 left"},
+		"none of":           {AnyOf{CollectAll{Kind: DataKindSigma}, ThreatUnder{Percent: 40}}, false, "Any of:"},
+		"nothing in any of": {AnyOf{}, false, "Any of:"},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.met, tc.cond.IsMet(&s))
+			assert.NotEmpty(t, tc.cond.Describe(CompatibilityAscii))
+			assert.Contains(t, tc.cond.Progress(&s, CompatibilityAscii), tc.progress)
+		})
+	}
+}
+
+func TestPanelShowsPercentages(t *testing.T) {
+	level := GetLevel(Tutorial)
+	level.WinConditions = []WinCondition{ThreatUnder{Percent: 50}}
+	s := NewGameState(level, CompatibilityAscii, testRand(), SystemClock)
+	s.player.Threat = level.MaxThreat / 4
+
+	// the panel prints the text as it is, so the percent signs have to come through untouched
+	var b strings.Builder
+	fmt.Fprint(&b, s.DataWanted(), s.DataCollected())
+	assert.Contains(t, b.String(), "Exit at <50% threat\n")
+	assert.Contains(t, b.String(), "Threat 25%")
+	assert.NotContains(t, b.String(), "%!")
+}
+
+func TestExitNeedsConditions(t *testing.T) {
+	level := GetLevel(Tutorial)
+	level.WinConditions = []WinCondition{ThreatUnder{Percent: 50}}
+	s := NewGameState(level, CompatibilityAscii, testRand(), SystemClock)
+	s.TickWorld()
+	assert.NotNil(t, s.world.Exit, "exit unlocks while threat is low")
+
+	// standing on the exit doesn't get the player out while their threat is too high
+	s.player.Location = *s.world.Exit
+	s.player.Threat = level.MaxThreat * 0.9
+	for i := 0; i < 100; i++ {
+		s.TickPlayer()
+	}
+	assert.False(t, s.IsComplete())
+
+	s.player.Threat = 0
+	for i := 0; i < 100 && !s.IsComplete(); i++ {
+		s.TickPlayer()
+	}
+	assert.True(t, s.IsComplete())
+}
@@ -16,6 +16,23 @@ func (c Coordinate) InRange(radius float64, other Coordinate) bool {
 	return math.Sqrt(rx*rx+ry*ry) < radius
 }
 
+// InCone is like InRange, but only includes coordinates within a cone of the given width (in degrees)
+// centered on the facing direction.
+func (c Coordinate) InCone(radius float64, facing Direction, width float64, other Coordinate) bool {
+	if !c.InRange(radius, other) {
+		return false
+	}
+	rx := float64(other.X - c.X)
+	ry := float64(other.Y-c.Y) * 2 // to compensate for terminal character sizes
+	if rx == 0 && ry == 0 {
+		return true
+	}
+	dx, dy := facing.Delta()
+	fx, fy := float64(dx), float64(dy)*2
+	cos := (rx*fx + ry*fy) / (math.Sqrt(rx*rx+ry*ry) * math.Sqrt(fx*fx+fy*fy))
+	return math.Acos(math.Max(-1, math.Min(1, cos)))*180/math.Pi <= width/2
+}
+
 func (c Coordinate) Equals(other Coordinate) bool {
 	return c.X == other.X && c.Y == other.Y
 }
@@ -33,6 +50,49 @@ const (
 	MoveDownRight
 )
 
+// Delta returns how far a single step in this direction moves along each axis.
+func (d Direction) Delta() (int, int) {
+	switch d {
+	case MoveUp:
+		return 0, -1
+	case MoveDown:
+		return 0, 1
+	case MoveLeft:
+		return -1, 0
+	case MoveRight:
+		return 1, 0
+	case MoveUpLeft:
+		return -1, -1
+	case MoveUpRight:
+		return 1, -1
+	case MoveDownLeft:
+		return -1, 1
+	case MoveDownRight:
+		return 1, 1
+	}
+	return 0, 0
+}
+
+// directionOf returns the direction of a single step, or false if it doesn't move at all.
+func directionOf(dx, dy int) (Direction, bool) {
+	for d := MoveUp; d <= MoveDownRight; d++ {
+		if x, y := d.Delta(); x == sign(dx) && y == sign(dy) {
+			return d, true
+		}
+	}
+	return MoveUp, false
+}
+
+func sign(v int) int {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+	return 0
+}
+
 type Rarity int
 
 const (
@@ -44,6 +104,13 @@ const (
 	Legendary
 )
 
+func (r Rarity) String() string {
+	if r < 0 || int(r) >= len(rarityNames) {
+		return "Unknown"
+	}
+	return rarityNames[r]
+}
+
 func (r Rarity) Color() Color {
 	switch r {
 	case Junk:
@@ -62,8 +129,8 @@ func (r Rarity) Color() Color {
 	return DarkGray
 }
 
-func getRarity(level *LevelConfig) Rarity {
-	v := rand.Float32()
+func getRarity(rng *rand.Rand, level *LevelConfig) Rarity {
+	v := rng.Float32()
 	if v > (1 - level.LootChanceByRarity[Legendary]) {
 		return Legendary
 	} else if v > (1 - level.LootChanceByRarity[Epic]) {
@@ -89,6 +156,13 @@ const (
 	DataKindOmega
 )
 
+func (k DataKind) String() string {
+	if k < 0 || int(k) >= len(dataKindNames) {
+		return "Unknown"
+	}
+	return dataKindNames[k]
+}
+
 func (k DataKind) ForMode(mode CompatibilityMode) string {
 	switch k {
 	case DataKindDelta:
@@ -115,6 +189,22 @@ const (
 	PowerUpLootSpeed
 )
 
+func (k PowerUpKind) String() string {
+	switch k {
+	case PowerUpVisionRange:
+		return "Vision"
+	case PowerUpThreatDecay:
+		return "Calm"
+	case PowerUpBadBitImmunity:
+		return "Immunity"
+	case PowerUpBadBitsAreGood:
+		return "Inversion"
+	case PowerUpLootSpeed:
+		return "Overclock"
+	}
+	return ""
+}
+
 func (k PowerUpKind) ForMode(mode CompatibilityMode) string {
 	switch k {
 	case PowerUpVisionRange:
@@ -148,6 +238,7 @@ func (lt *lootTable) Len() int {
 type lootOption struct {
 	Data    DataKind
 	PowerUp PowerUpKind
+	Item    ItemKind
 	Chance  float32
 }
 
@@ -157,6 +248,7 @@ const (
 	LootEmpty LootKind = iota
 	LootData
 	LootPowerUp
+	LootItem
 )
 
 type Loot struct {
@@ -164,12 +256,13 @@ type Loot struct {
 	Rarity      Rarity
 	PowerUpKind PowerUpKind
 	DataKind    DataKind
+	ItemKind    ItemKind
 	Data        float32
 	Integrity   float32 // set to 1 initially, when it hits 0, the loot becomes worthless and disappears
 }
 
-func newLoot(kind LootKind, level *LevelConfig) Loot {
-	rarity := getRarity(level)
+func newLoot(rng *rand.Rand, kind LootKind, level *LevelConfig) Loot {
+	rarity := getRarity(rng, level)
 	loot := Loot{
 		Kind:      kind,
 		Rarity:    rarity,
@@ -177,12 +270,14 @@ func newLoot(kind LootKind, level *LevelConfig) Loot {
 	}
 	switch kind {
 	case LootData:
-		dataKind := level.DataLootTable[pickOne(&level.DataLootTable)].Data
+		dataKind := level.DataLootTable[pickOne(rng, &level.DataLootTable)].Data
 		data := level.DataByRarity[rarity] * level.DataMultipliers[dataKind]
 		loot.Data, loot.DataKind = data, dataKind
 	case LootPowerUp:
-		powerUpKind := level.PowerUpLootTable[pickOne(&level.PowerUpLootTable)].PowerUp
+		powerUpKind := level.PowerUpLootTable[pickOne(rng, &level.PowerUpLootTable)].PowerUp
 		loot.PowerUpKind = powerUpKind
+	case LootItem:
+		loot.ItemKind = level.ItemLootTable[pickOne(rng, &level.ItemLootTable)].Item
 	}
 	return loot
 }
@@ -193,6 +288,8 @@ func (l *Loot) SymbolForMode(mode CompatibilityMode) (Color, string) {
 		return l.Rarity.Color(), l.DataKind.ForMode(mode)
 	case LootPowerUp:
 		return l.Rarity.Color(), l.PowerUpKind.ForMode(mode)
+	case LootItem:
+		return l.Rarity.Color(), l.ItemKind.ForMode(mode)
 	}
 	return l.Rarity.Color(), ` `
 }
@@ -236,17 +333,28 @@ type World struct {
 	Footprints           map[Coordinate]Footprint
 	DataSpawnProgress    float32
 	PowerUpSpawnProgress float32
+	ItemSpawnProgress    float32
 	Exit                 *Coordinate
+	Objects              []WorldObject
+	Enemies              []Enemy
+	Walls                map[Coordinate]bool
+	Despawned            map[DataKind]int // how much data of each kind has decayed away before being looted
+	rng                  *rand.Rand
 }
 
-func newWorld(level *LevelConfig) World {
+func newWorld(level *LevelConfig, rng *rand.Rand) World {
 	world := World{
 		Level:      level,
 		Loot:       make(map[Coordinate]Loot),
 		Footprints: make(map[Coordinate]Footprint),
+		Walls:      newWalls(level, rng),
+		Despawned:  make(map[DataKind]int),
+		rng:        rng,
 	}
 	world.spawnLoot(level.InitialData, LootData)
 	world.spawnLoot(level.InitialPowerUps, LootPowerUp)
+	world.spawnLoot(level.InitialItems, LootItem)
+	world.spawnEnemies()
 	return world
 }
 
@@ -268,16 +376,28 @@ func (w *World) ExtractLoot(c Coordinate) Loot {
 }
 
 func (w *World) spawnLoot(n int, kind LootKind) {
+	spots := w.Level.Layout.lootSpots(kind)
 	filled := 0
 	for filled < n {
 		// make sure it's empty first
-		x := rand.Intn(w.Level.Width - 1)
-		y := rand.Intn(w.Level.Height - 1)
-		c := Coordinate{x, y}
+		c := w.randomOpenCell()
+		if len(spots) > 0 {
+			// hand-drawn maps say where loot goes, so there might not be room for it all
+			free := make([]Coordinate, 0, len(spots))
+			for _, spot := range spots {
+				if w.Loot[spot].Kind == LootEmpty {
+					free = append(free, spot)
+				}
+			}
+			if len(free) == 0 {
+				return
+			}
+			c = free[w.rng.Intn(len(free))]
+		}
 
 		// even though it's a sparse map, this should work due to default types in go :squint:
 		if w.Loot[c].Kind == LootEmpty {
-			w.Loot[c] = newLoot(kind, w.Level)
+			w.Loot[c] = newLoot(w.rng, kind, w.Level)
 			filled++
 		}
 	}
@@ -294,9 +414,12 @@ func (w *World) TickLoot() {
 	// tick all existing loot
 	newLoot := make(map[Coordinate]Loot)
 	for c, loot := range w.Loot {
+		wasData := loot.Kind == LootData
 		loot.tick(w.Level.DataDecayRate)
 		if loot.Kind != LootEmpty {
 			newLoot[c] = loot
+		} else if wasData {
+			w.Despawned[loot.DataKind]++
 		}
 	}
 	w.Loot = newLoot
@@ -317,13 +440,18 @@ func (w *World) TickLoot() {
 		w.PowerUpSpawnProgress = 0
 		w.spawnLoot(1, LootPowerUp)
 	}
+	w.ItemSpawnProgress += w.Level.ItemSpawnRate
+	if w.ItemSpawnProgress > 1 {
+		w.ItemSpawnProgress = 0
+		w.spawnLoot(1, LootItem)
+	}
 }
 
-func (w *World) UnlockExit() {
+// UnlockExit opens up the exit, somewhere out of the player's way.
+func (w *World) UnlockExit(player Coordinate) {
 	if w.Exit == nil {
-		x := rand.Intn(w.Level.Width - 1)
-		y := rand.Intn(w.Level.Height - 1)
-		w.Exit = &Coordinate{x, y}
+		c := w.placeExit(player)
+		w.Exit = &c
 	}
 }
 
@@ -5,6 +5,7 @@ import (
 	"log"
 	"math/rand"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,6 +18,7 @@ const (
 	threatView      = "threat"
 	progressBarView = "progress"
 	itemsView       = "items"
+	branchesView    = "branches"
 )
 
 var (
@@ -24,49 +26,127 @@ var (
 	// we need this to be global so we can replace it when the level is over.
 	state     zen_doctor.GameState
 	collected = make([]zen_doctor.Loot, 0)
-	lastTick  = time.Now()
+	visited   = make([]zen_doctor.Level, 0)
 	elapsed   = 0 * time.Millisecond
 	mode      = zen_doctor.CompatibilityAny
 	cheatMode = false
+	campaign  = zen_doctor.DefaultCampaign
+	// everything random in the game comes from here, so --seed can play the same game again
+	rng *rand.Rand
+	// the game runs on its own clock, which the engine moves along as game time goes by. Game time stops while the
+	// game is paused or between levels, and runs at whatever speed was asked for.
+	clock     = zen_doctor.NewManualClock(time.Now())
+	engine    *zen_doctor.Engine
+	gameClock = zen_doctor.NewGameClock(zen_doctor.SystemClock)
+	lastTick  = gameClock.Now() // the game time the engine has been stepped up to
+	speed     = 1.0
 )
 
 func main() {
-	rand.Seed(time.Now().Unix())
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		os.Exit(validateLevels(os.Args[2:]))
+	}
+	if len(os.Args) > 2 && os.Args[1] == "edit" {
+		if err := parseArgs(os.Args[3:]); err != nil {
+			log.Fatalln(err)
+		}
+		if err := editLevel(os.Args[2]); err != nil {
+			log.Fatalln(err)
+		}
+		return
+	}
+	if err := parseArgs(os.Args[1:]); err != nil {
+		log.Fatalln(err)
+	}
+	if err := play(); err != nil {
+		log.Panicln(err)
+	}
+}
+
+// play runs the game until the player quits.
+func play() error {
+	done = make(chan bool)
+	collected = collected[:0]
+	visited = visited[:0]
+	gameClock = zen_doctor.NewGameClock(zen_doctor.SystemClock)
+	gameClock.SetScale(speed)
+	lastTick = gameClock.Now()
+	elapsed = 0 * time.Millisecond
+	cheatMode = false
+
 	g, err := gocui.NewGui(gocui.Output256)
 	if err != nil {
-		log.Panicln(err)
+		return err
 	}
 	defer g.Close()
 
-	mode = parseArgs()
 	if mode == zen_doctor.CompatibilityAscii {
 		g.ASCII = true
 	}
 	g.Highlight = true
 	g.SelFgColor = gocui.ColorGreen
 
-	state = zen_doctor.NewGameState(zen_doctor.Tutorial, mode)
+	first, _ := campaign.Get(campaign.First())
+	state = zen_doctor.NewGameState(first, mode, rng, clock)
 	if err := initGame(g, &state); err != nil {
-		log.Panicln(err)
+		return err
 	}
 
 	// start the terminal display loop
 	if err := g.MainLoop(); err != nil && err != gocui.ErrQuit {
-		log.Panicln(err)
+		return err
 	}
+	return nil
 }
 
-func parseArgs() zen_doctor.CompatibilityMode {
-	mode := zen_doctor.CompatibilityAny
-	for _, arg := range os.Args {
-		switch strings.ToLower(arg) {
+func parseArgs(args []string) error {
+	endless := false
+	seed := time.Now().UnixNano()
+	for i := 0; i < len(args); i++ {
+		switch strings.ToLower(args[i]) {
 		case "--ascii":
 			mode = zen_doctor.CompatibilityAscii
 		case "--latin":
 			mode = zen_doctor.CompatibilityLatin
+		case "--levels":
+			if i+1 >= len(args) {
+				return errors.New("--levels needs a directory of level files")
+			}
+			i++
+			loaded, err := zen_doctor.LoadCampaign(args[i])
+			if err != nil {
+				return err
+			}
+			campaign = loaded
+		case "--endless":
+			endless = true
+		case "--seed":
+			if i+1 >= len(args) {
+				return errors.New("--seed needs a number")
+			}
+			i++
+			parsed, err := strconv.ParseInt(args[i], 10, 64)
+			if err != nil {
+				return errors.Wrapf(err, "parsing seed %q", args[i])
+			}
+			seed = parsed
+		case "--speed":
+			if i+1 >= len(args) {
+				return errors.New("--speed needs a number")
+			}
+			i++
+			parsed, err := strconv.ParseFloat(args[i], 64)
+			if err != nil || parsed <= 0 {
+				return errors.Errorf("--speed needs a number above 0, got %q", args[i])
+			}
+			speed = parsed
 		}
 	}
-	return mode
+	if endless {
+		campaign = zen_doctor.NewEndlessCampaign(campaign, seed)
+	}
+	rng = rand.New(rand.NewSource(seed))
+	return nil
 }
 
 func quit(_ *gocui.Gui, _ *gocui.View) error {
@@ -94,7 +174,8 @@ func initGame(g *gocui.Gui, state *zen_doctor.GameState) error {
 		return err
 	}
 	// start the game loop
-	go gameLoop(g, state)
+	engine = zen_doctor.NewEngine(state, clock)
+	go gameLoop(g, engine)
 
 	return nil
 }
@@ -158,19 +239,25 @@ func layout(state *zen_doctor.GameState) func(g *gocui.Gui) error {
 func renderInventory(v *gocui.View, state *zen_doctor.GameState) {
 	v.Clear()
 	fmt.Fprintln(v, "Want:")
-	fmt.Fprintf(v, state.DataWanted())
-	fmt.Fprintf(v, strings.Repeat("─", 18))
+	fmt.Fprint(v, state.DataWanted())
+	fmt.Fprint(v, strings.Repeat("─", 18))
 	fmt.Fprintln(v, "Have:")
-	fmt.Fprintf(v, state.DataCollected())
-	fmt.Fprintf(v, strings.Repeat("─", 18))
+	fmt.Fprint(v, state.DataCollected())
+	fmt.Fprint(v, strings.Repeat("─", 18))
 	fmt.Fprintln(v, "Collected:")
 	b := strings.Builder{}
 	for _, have := range state.Inventory() {
 		b.WriteString(zen_doctor.WithColor(have.SymbolForMode(mode)))
 	}
 	fmt.Fprintln(v, b.String())
-	fmt.Fprintf(v, strings.Repeat("─", 18))
-	fmt.Fprintf(v, zen_doctor.ElapsedTime(elapsed))
+	fmt.Fprint(v, strings.Repeat("─", 18))
+	fmt.Fprintln(v, "Hotbar:")
+	fmt.Fprintln(v, state.Hotbar())
+	fmt.Fprint(v, strings.Repeat("─", 18))
+	fmt.Fprintln(v, "Active:")
+	fmt.Fprint(v, state.ActivePowerUps())
+	fmt.Fprint(v, strings.Repeat("─", 18))
+	fmt.Fprint(v, zen_doctor.ElapsedTime(elapsed))
 }
 
 func gameKeybinds(g *gocui.Gui, state *zen_doctor.GameState) error {
@@ -208,6 +295,13 @@ func gameKeybinds(g *gocui.Gui, state *zen_doctor.GameState) error {
 		return err
 	}
 
+	// items
+	for slot := 0; slot < zen_doctor.MaxItems; slot++ {
+		if err := g.SetKeybinding(level.Name(), rune('1'+slot), gocui.ModNone, useItem(state, slot)); err != nil {
+			return err
+		}
+	}
+
 	// pause
 	if err := g.SetKeybinding(level.Name(), gocui.KeySpace, gocui.ModNone, pause); err != nil {
 		return err
@@ -224,6 +318,15 @@ func movePlayer(state *zen_doctor.GameState, dir zen_doctor.Direction) func(g *g
 	}
 }
 
+func useItem(state *zen_doctor.GameState, slot int) func(g *gocui.Gui, v *gocui.View) error {
+	return func(g *gocui.Gui, v *gocui.View) error {
+		state.UseItem(slot)
+		v.Clear()
+		fmt.Fprintf(v, "%s", state.String())
+		return nil
+	}
+}
+
 // kills the game's goroutine and shows a pause window
 func pause(g *gocui.Gui, _ *gocui.View) error {
 	maxX, maxY := g.Size()
@@ -234,6 +337,7 @@ func pause(g *gocui.Gui, _ *gocui.View) error {
 		}
 		g.SetCurrentView("pause")
 		done <- true
+		gameClock.Pause()
 		v.Title = "Paused"
 		fmt.Fprintln(v, "Press space to resume")
 
@@ -248,17 +352,32 @@ func resume(g *gocui.Gui, _ *gocui.View) error {
 	g.SetCurrentView(state.Level().Name())
 	g.DeleteView("pause")
 	g.DeleteKeybindings("pause")
-	lastTick = time.Now()
-	go gameLoop(g, &state)
+	gameClock.Resume()
+	go gameLoop(g, engine)
 	return nil
 }
 
+// lets the player know they've been detected, and the hunters are on their way
+func raiseAlarm(g *gocui.Gui) {
+	g.SelFgColor = gocui.ColorRed
+	if v, err := g.View(threatView); err == nil {
+		v.Title = "ALARM! Hunters unleashed - get to the exit"
+	}
+}
+
 func gameOver(g *gocui.Gui, didWin bool) error {
 	// copy over inventory to our final collection
 	collected = append(collected, state.Inventory()...)
+	return showGameOver(g, didWin)
+}
+
+// showGameOver ends the run with whatever has been collected so far, for when the current level's inventory has
+// already been counted.
+func showGameOver(g *gocui.Gui, didWin bool) error {
+	gameClock.Pause()
 
 	maxX, maxY := g.Size()
-	gameOverText := zen_doctor.GameOver(didWin, elapsed, mode, collected...)
+	gameOverText := zen_doctor.GameOver(didWin, state.LoseReason(), elapsed, mode, collected...)
 	x1, y1, x2, y2 := zen_doctor.CalculateViewPosition(80, 8, maxX, maxY)
 	if v, err := g.SetView("game over", x1, y1, x2, y2); err != nil {
 		if err != gocui.ErrUnknownView {
@@ -271,6 +390,12 @@ func gameOver(g *gocui.Gui, didWin bool) error {
 		} else if didWin {
 			g.SelFgColor = gocui.ColorGreen
 			v.Title = "YOU WIN"
+		} else if state.IsFailed() {
+			g.SelFgColor = gocui.ColorRed
+			v.Title = "MISSION FAILED"
+		} else if state.Level().HunterCount > 0 {
+			g.SelFgColor = gocui.ColorRed
+			v.Title = "HUNTED DOWN"
 		} else {
 			g.SelFgColor = gocui.ColorRed
 			v.Title = "GAME OVER"
@@ -287,10 +412,13 @@ func restart(g *gocui.Gui, _ *gocui.View) error {
 	g.DeleteView("game over")
 	g.DeleteKeybindings("game over")
 	g.SelFgColor = gocui.ColorGreen
-	lastTick = time.Now()
+	gameClock.Resume()
 	elapsed = 0 * time.Millisecond
 	cheatMode = false
-	state = zen_doctor.NewGameState(zen_doctor.Tutorial, mode)
+	collected = collected[:0]
+	visited = visited[:0]
+	first, _ := campaign.Get(campaign.First())
+	state = zen_doctor.NewGameState(first, mode, rng, clock)
 	if err := initGame(g, &state); err != nil {
 		return err
 	}
@@ -300,72 +428,130 @@ func restart(g *gocui.Gui, _ *gocui.View) error {
 func nextLevel(g *gocui.Gui) error {
 	// keep going until they run out of levels - if they make it all the way, winner winner chicken dinner!
 	current := state.Level()
-	next := current.Level.Inc()
-	if !next.IsValid() {
+	progress := zen_doctor.Progress{
+		Visited:   append(append([]zen_doctor.Level{}, visited...), current.Level),
+		Collected: append(append([]zen_doctor.Loot{}, collected...), state.Inventory()...),
+	}
+	branches := campaign.Next(current.Level, progress)
+	if len(branches) == 0 {
 		return gameOver(g, true)
 	}
+
+	// copy over inventory to our final collection
+	visited = progress.Visited
+	collected = progress.Collected
+	if len(branches) == 1 {
+		return enterLevel(g, branches[0].Level)
+	}
+	return chooseBranch(g, branches)
+}
+
+// shows the ways forward from the current level, and lets the player pick one
+func chooseBranch(g *gocui.Gui, branches []zen_doctor.Branch) error {
+	maxX, maxY := g.Size()
+	x1, y1, x2, y2 := zen_doctor.CalculateViewPosition(80, len(branches)+3, maxX, maxY)
+	v, err := g.SetView(branchesView, x1, y1, x2, y2)
+	if err != nil && err != gocui.ErrUnknownView {
+		return err
+	}
+	g.SetCurrentView(branchesView)
+	gameClock.Pause()
+	v.Title = "Choose your route"
+	fmt.Fprintln(v, "The way forward splits:")
+	fmt.Fprintln(v)
+	for i, branch := range branches {
+		next, _ := campaign.Get(branch.Level)
+		if branch.Label != "" {
+			fmt.Fprintf(v, "%d. %s - %s\n", i+1, next.Name(), branch.Label)
+		} else {
+			fmt.Fprintf(v, "%d. %s\n", i+1, next.Name())
+		}
+
+		level := branch.Level
+		if err := g.SetKeybinding(branchesView, rune('1'+i), gocui.ModNone, func(g *gocui.Gui, _ *gocui.View) error {
+			g.DeleteView(branchesView)
+			g.DeleteKeybindings(branchesView)
+			gameClock.Resume()
+			return enterLevel(g, level)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cleans up the current level, and starts the next one with the player where they left off
+func enterLevel(g *gocui.Gui, level zen_doctor.Level) error {
+	next, ok := campaign.Get(level)
+	if !ok {
+		// nextLevel has already added this level's inventory to what was collected
+		return showGameOver(g, true)
+	}
 	// clean up old view
+	current := state.Level()
 	g.DeleteKeybindings(current.Name())
 	g.DeleteView(current.Name())
-
-	// copy over inventory to our final collection
-	collected = append(collected, state.Inventory()...)
+	g.SelFgColor = gocui.ColorGreen
 
 	// create new state and initialize
 	loc := state.PlayerLocation()
-	state = zen_doctor.NewGameStateWithPlayerAt(loc, next, mode)
+	state = zen_doctor.NewGameStateWithPlayerAt(loc, next, mode, rng, clock)
 	return initGame(g, &state)
 }
 
 func skipToLevel(level zen_doctor.Level) func(*gocui.Gui, *gocui.View) error {
 	return func(g *gocui.Gui, _ *gocui.View) error {
+		next, ok := campaign.Get(level)
+		if !ok {
+			return nil
+		}
 		// clean up the current level and set cheat mode to true to tattle on them at the end
 		done <- true
 		cheatMode = true
 		current := state.Level()
 		g.DeleteKeybindings(current.Name())
 		g.DeleteView(current.Name())
+		g.SelFgColor = gocui.ColorGreen
 
 		// initialize the requested level
-		state = zen_doctor.NewGameState(level, mode)
+		state = zen_doctor.NewGameState(next, mode, rng, clock)
 		return initGame(g, &state)
 	}
 }
 
-func gameLoop(g *gocui.Gui, state *zen_doctor.GameState) {
+// gameLoop keeps the engine up with real time, and redraws the game as it goes. The engine decides how fast everything
+// in the game happens - this only decides how often to catch up with it.
+func gameLoop(g *gocui.Gui, engine *zen_doctor.Engine) {
+	state := engine.State()
 	level := state.Level()
 
-	viewUpdate := time.NewTicker(time.Duration(1000/level.FPS) * time.Millisecond)
-	defer viewUpdate.Stop()
-
-	fixedUpdate := time.NewTicker((1000 / 30) * time.Millisecond)
-	defer fixedUpdate.Stop()
-
-	animationUpdate := time.NewTicker((1000 / 11) * time.Millisecond)
-	defer animationUpdate.Stop()
-
-	automoveUpdate := time.NewTicker((1000 / 7) * time.Millisecond)
-	defer automoveUpdate.Stop()
+	frame := time.NewTicker((1000 / 30) * time.Millisecond)
+	defer frame.Stop()
 
 	for {
 		select {
 		case <-done:
 			return
 
-		// Fixed update
-		case <-fixedUpdate.C:
-			now := time.Now()
+		case <-frame.C:
+			now := gameClock.Now()
 			elapsed = elapsed + now.Sub(lastTick)
+			engine.Step(now.Sub(lastTick))
 			lastTick = now
 
-			state.TickWorld()
-			state.TickPlayer()
 			g.Update(func(g *gocui.Gui) error {
 				// threat view
 				if v, err := g.View(threatView); err == nil {
 					v.Clear()
+					v.Title = "Threat"
+					if left, ok := state.TimeLeft(); ok {
+						v.Title = fmt.Sprintf("Threat - %s left", zen_doctor.ElapsedTime(left))
+					}
 					fmt.Fprintf(v, "%s", state.ThreatMeter())
 				}
+				if state.IsAlarmRaised() {
+					raiseAlarm(g)
+				}
 				// loot view
 				if v, err := g.View(progressBarView); err == nil {
 					v.Clear()
@@ -391,18 +577,6 @@ func gameLoop(g *gocui.Gui, state *zen_doctor.GameState) {
 				}
 				return nil
 			})
-
-		// Game time update
-		case <-viewUpdate.C:
-			state.TickBitStream()
-
-		// animations run at a different speed
-		case <-animationUpdate.C:
-			state.TickAnimations()
-
-			// automatic movement
-		case <-automoveUpdate.C:
-			state.TickMovement()
 		}
 	}
 }
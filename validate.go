@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	zen_doctor "github.com/krixi/zen-doctor/internal"
+)
+
+// validateLevels checks each level file, or every level file in each directory, and prints out any problems.
+// It returns the exit code for the process.
+func validateLevels(paths []string) int {
+	if len(paths) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: zen-doctor validate <file|dir>...")
+		return 2
+	}
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		found, err := zen_doctor.LevelFiles(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		files = append(files, found...)
+	}
+
+	failed := 0
+	for _, file := range files {
+		level, err := zen_doctor.LoadLevel(file)
+		if err != nil {
+			fmt.Printf("%s: %s\n", file, err)
+			failed++
+			continue
+		}
+		errs := level.Validate()
+		for _, err := range errs {
+			fmt.Printf("%s: %s\n", file, err)
+		}
+		if len(errs) > 0 {
+			failed++
+		} else {
+			fmt.Printf("%s: ok\n", file)
+		}
+	}
+	if failed > 0 {
+		fmt.Printf("%d of %d levels are not valid\n", failed, len(files))
+		return 1
+	}
+	return 0
+}